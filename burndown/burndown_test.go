@@ -0,0 +1,867 @@
+package burndown_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"reports/burndown"
+	agile "reports/jira"
+	"reports/jira/jiratest"
+	"strings"
+	"testing"
+	"time"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+func at(day, hour int) time.Time {
+	return time.Date(2026, time.October, day, hour, 0, 0, 0, time.UTC)
+}
+
+// sprintFixture is a sprint from Monday 5th to Friday 9th with:
+//
+//	A-1 8h, started on Tuesday and done on Wednesday with 6h logged
+//	A-2 4h, added on Tuesday
+//	A-3 2h, removed on Wednesday
+//	B-1 on the board but never in the sprint
+func sprintFixture() jiratest.Fixture {
+	start, end := at(5, 9), at(9, 17)
+	return jiratest.Fixture{
+		Boards: []jiratest.Board{{Board: jira.Board{ID: 1, Name: "Team board", Type: "scrum", FilterID: 100}}},
+		Sprints: []jira.Sprint{
+			{ID: 9, Name: "Sprint 9", State: "closed", OriginBoardID: 1},
+			{ID: 10, Name: "Sprint 10", State: "active", StartDate: &start, EndDate: &end, OriginBoardID: 1},
+		},
+		Statuses: []jira.Status{
+			{Name: "Open", StatusCategory: jira.StatusCategory{Key: "new"}},
+			{Name: "In Progress", StatusCategory: jira.StatusCategory{Key: jira.StatusCategoryInProgress}},
+			{Name: "Done", StatusCategory: jira.StatusCategory{Key: jira.StatusCategoryComplete}},
+		},
+		Issues: []jiratest.Issue{
+			{
+				Key: "A-1", Status: "Done", Sprints: []int{10},
+				Fields: map[string]interface{}{"timeestimate": 0, "timespent": 21600},
+				Changelog: []jiratest.Change{
+					{Created: at(6, 10), Items: []jira.ChangelogItems{jiratest.StatusChange("Open", "In Progress")}},
+					{Created: at(7, 12), Items: []jira.ChangelogItems{
+						jiratest.StatusChange("In Progress", "Done"),
+						jiratest.FieldChange("timeestimate", 28800, 0),
+						jiratest.FieldChange("timespent", 0, 21600),
+					}},
+				},
+			},
+			{
+				Key: "A-2", Status: "Open", Sprints: []int{10},
+				Fields: map[string]interface{}{"timeestimate": 14400},
+				Changelog: []jiratest.Change{
+					{Created: at(6, 11), Items: []jira.ChangelogItems{jiratest.SprintChange(nil, []int{10})}},
+				},
+			},
+			{
+				Key: "A-3", Status: "Open",
+				Fields: map[string]interface{}{"timeestimate": 7200},
+				Changelog: []jiratest.Change{
+					{Created: at(7, 9), Items: []jira.ChangelogItems{jiratest.SprintChange([]int{10}, nil)}},
+				},
+			},
+			{
+				Key: "B-1", Status: "Open",
+				Fields: map[string]interface{}{"timeestimate": 3600},
+				Changelog: []jiratest.Change{
+					{Created: at(6, 9), Items: []jira.ChangelogItems{jiratest.StatusChange("Open", "Open")}},
+				},
+			},
+		},
+	}
+}
+
+type series struct {
+	Buckets []string `json:"buckets"`
+	Series  []struct {
+		Time      *time.Time `json:"time"`
+		WorkHours *float64   `json:"workHours"`
+		Remaining []float64  `json:"remaining"`
+		Scope     float64    `json:"scope"`
+	} `json:"series"`
+	Guideline []struct {
+		Value float64 `json:"value"`
+	} `json:"guideline"`
+}
+
+func client(t *testing.T, srv *jiratest.Server) *agile.Client {
+	c, err := agile.InitJira("user", "pass", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// renderer is a generated report
+type renderer interface {
+	Render(w io.Writer, f burndown.Format) error
+}
+
+// render gives the report in the format
+func render(t *testing.T, r renderer, f burndown.Format) string {
+	var b bytes.Buffer
+	err := r.Render(&b, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+// decode renders the report as JSON and decodes it into v
+func decode(t *testing.T, r renderer, v interface{}) {
+	err := json.Unmarshal([]byte(render(t, r, burndown.JSON)), v)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func generate(t *testing.T, opts burndown.Opts) series {
+	r, err := burndown.Generate(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s series
+	decode(t, r, &s)
+	return s
+}
+
+func TestBurndown(t *testing.T) {
+	columns := sprintFixture()
+	columns.Boards[0].Columns = []agile.Column{
+		{Name: "To Do", Statuses: []string{"Open"}},
+		{Name: "Doing", Statuses: []string{"In Progress"}},
+		{Name: "Done", Statuses: []string{"Done"}},
+	}
+	kanban := sprintFixture()
+	kanban.Boards = append(kanban.Boards, jiratest.Board{Board: jira.Board{ID: 2, Name: "Kanban", Type: "kanban", FilterID: 200}, Filter: "project = A"})
+	assignees := sprintFixture()
+	assignees.Issues[0].Assignee = "Alice"
+	assignees.Issues[1].Assignee = "Bob"
+	assignees.Issues[1].Changelog = append(assignees.Issues[1].Changelog, jiratest.Change{Created: at(8, 10), Items: []jira.ChangelogItems{jiratest.AssigneeChange("Alice", "Bob")}})
+	created := sprintFixture()
+	created.Issues = append(created.Issues, jiratest.Issue{
+		Key: "A-4", Status: "Open", Sprints: []int{10}, Created: at(8, 10),
+		Fields: map[string]interface{}{"timeestimate": 10800},
+	})
+	points := sprintFixture()
+	points.Boards[0].Estimation = agile.EstimationField{FieldID: "customfield_10002", Name: "Story Points"}
+	for i, p := range []float64{5, 3, 2} {
+		points.Issues[i].Fields["customfield_10002"] = p
+	}
+
+	tests := []struct {
+		name    string
+		fixture jiratest.Fixture
+		opts    burndown.Opts
+		buckets []string
+		// workHours and ideal, the first and last value of the guideline, are not checked if nil
+		workHours []float64
+		ideal     []float64
+		remaining [][]float64
+		scope     []float64
+	}{
+		{
+			name: "sprint", fixture: sprintFixture(), opts: burndown.Opts{Board: "Team board", Sprint: "Sprint 10"},
+			buckets: []string{"New", "In Progress"}, workHours: []float64{0, 9, 10, 16, 19}, ideal: []float64{10, 0},
+			remaining: [][]float64{{10, 0}, {2, 8}, {6, 8}, {4, 8}, {4, 0}}, scope: []float64{0, 0, 4, 2, 2},
+		},
+		{
+			//A-4 is created in the sprint on Thursday, without a sprint change in its changelog
+			name: "created in the sprint", fixture: created, opts: burndown.Opts{Board: "Team board", Sprint: "Sprint 10"},
+			buckets:   []string{"New", "In Progress"},
+			remaining: [][]float64{{10, 0}, {2, 8}, {6, 8}, {4, 8}, {4, 0}, {7, 0}}, scope: []float64{0, 0, 4, 2, 2, 5},
+		},
+		{
+			name: "board columns", fixture: columns, opts: burndown.Opts{Sprint: "10", BoardColumns: true, FullTimeline: true},
+			buckets:   []string{"To Do", "Doing"},
+			remaining: [][]float64{{10, 0}, {2, 8}, {6, 8}, {4, 8}, {4, 0}}, scope: []float64{0, 0, 4, 2, 2},
+		},
+		{
+			//All issues of project A, A-1 is still in progress at the end as it is done after the time range
+			name: "kanban time range", fixture: kanban, opts: burndown.Opts{Board: "Kanban", From: at(5, 9), To: at(7, 11)},
+			buckets:   []string{"New", "In Progress"},
+			remaining: [][]float64{{14, 0}, {6, 8}}, scope: []float64{0, 0},
+		},
+		{
+			//A-2 was added for Alice and reassigned to Bob, A-3 was removed
+			name: "by assignee", fixture: assignees, opts: burndown.Opts{Board: "Team board", ByAssignee: true},
+			buckets:   []string{"Alice", "Bob", burndown.Unassigned},
+			remaining: [][]float64{{8, 0, 2}, {8, 0, 2}, {12, 0, 2}, {12, 0, 0}, {4, 0, 0}, {0, 4, 0}}, scope: []float64{0, 0, 4, 2, 2, 2},
+		},
+		{
+			name: "story points", fixture: points, opts: burndown.Opts{Board: "Team board", Sprint: "Sprint 10", Metric: burndown.StoryPoints},
+			buckets:   []string{"New", "In Progress"},
+			remaining: [][]float64{{7, 0}, {2, 5}, {5, 5}, {3, 5}, {3, 0}}, scope: []float64{0, 0, 3, 1, 1},
+		},
+		{
+			name: "issue count", fixture: points, opts: burndown.Opts{Board: "Team board", Sprint: "Sprint 10", Metric: burndown.IssueCount},
+			buckets:   []string{"New", "In Progress"},
+			remaining: [][]float64{{2, 0}, {1, 1}, {2, 1}, {1, 1}, {1, 0}}, scope: []float64{0, 0, 1, 0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := jiratest.NewServer(tt.fixture)
+			defer srv.Close()
+			opts := tt.opts
+			opts.Client, opts.WorkdayStart, opts.WorkdayEnd = client(t, srv), 9, 17
+
+			s := generate(t, opts)
+			if !reflect.DeepEqual(s.Buckets, tt.buckets) {
+				t.Errorf("buckets = %v, want %v", s.Buckets, tt.buckets)
+			}
+			var workHours, scope []float64
+			var remaining [][]float64
+			for _, p := range s.Series {
+				if p.WorkHours != nil {
+					workHours = append(workHours, *p.WorkHours)
+				}
+				remaining, scope = append(remaining, p.Remaining), append(scope, p.Scope)
+			}
+			if tt.workHours != nil && !reflect.DeepEqual(workHours, tt.workHours) {
+				t.Errorf("work hours = %v, want %v", workHours, tt.workHours)
+			}
+			if tt.opts.FullTimeline && len(workHours) > 0 {
+				t.Errorf("work hours = %v, want none in full timeline mode", workHours)
+			}
+			if !reflect.DeepEqual(remaining, tt.remaining) {
+				t.Errorf("remaining = %v, want %v", remaining, tt.remaining)
+			}
+			if !reflect.DeepEqual(scope, tt.scope) {
+				t.Errorf("scope = %v, want %v", scope, tt.scope)
+			}
+			if g := s.Guideline; tt.ideal != nil && (len(g) == 0 || g[0].Value != tt.ideal[0] || g[len(g)-1].Value != tt.ideal[1]) {
+				t.Errorf("guideline = %+v, want from %v to %v", g, tt.ideal[0], tt.ideal[1])
+			}
+		})
+	}
+}
+
+func TestBurndownErrors(t *testing.T) {
+	noActiveSprint := sprintFixture()
+	noActiveSprint.Sprints = noActiveSprint.Sprints[:1]
+	tests := []struct {
+		name    string
+		fixture jiratest.Fixture
+		opts    burndown.Opts
+		// is tells if the error is the expected one
+		is func(error) bool
+	}{
+		{
+			name: "no active sprint", fixture: noActiveSprint, opts: burndown.Opts{Board: "1", WorkdayStart: 9, WorkdayEnd: 17},
+			is: func(err error) bool {
+				step, ok := err.(*burndown.StepError)
+				return ok && step.Err == agile.ErrNoActiveSprint
+			},
+		},
+		{
+			name: "empty working day", fixture: sprintFixture(), opts: burndown.Opts{Board: "1", WorkdayStart: 9, WorkdayEnd: 9},
+			is: func(err error) bool {
+				_, ok := err.(*burndown.OptionError)
+				return ok
+			},
+		},
+		{
+			name: "board without estimation field", fixture: sprintFixture(),
+			opts: burndown.Opts{Board: "Team board", Sprint: "Sprint 10", Metric: burndown.StoryPoints, WorkdayStart: 9, WorkdayEnd: 17},
+			is: func(err error) bool {
+				step, ok := err.(*burndown.StepError)
+				return ok && step.Step == "select metric" &&
+					step.Err.Error() == "board 'Team board' does not use a custom field for estimation (uses 'Remaining Estimate')"
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := jiratest.NewServer(tt.fixture)
+			defer srv.Close()
+			opts := tt.opts
+			opts.Client = client(t, srv)
+
+			_, err := burndown.Generate(context.Background(), opts)
+			if !tt.is(err) {
+				t.Errorf("err = %v", err)
+			}
+		})
+	}
+}
+
+func TestBurndownTruncatedChangelog(t *testing.T) {
+	f := sprintFixture()
+	f.ChangelogLimit = 1
+	srv := jiratest.NewServer(f)
+	defer srv.Close()
+
+	s := generate(t, burndown.Opts{Client: client(t, srv), Sprint: "10", WorkdayStart: 9, WorkdayEnd: 17})
+
+	last := s.Series[len(s.Series)-1]
+	if !reflect.DeepEqual(last.Remaining, []float64{4, 0}) {
+		t.Errorf("remaining at end = %v, want [4 0]", last.Remaining)
+	}
+	var paged bool
+	for _, r := range srv.Requests() {
+		paged = paged || strings.HasPrefix(r, "/rest/api/2/issue/A-1/changelog")
+	}
+	if !paged {
+		t.Errorf("truncated changelog of A-1 was not requested, requests: %v", srv.Requests())
+	}
+}
+
+func TestBurndownBoardTimeRangeSearch(t *testing.T) {
+	f := sprintFixture()
+	f.Boards = append(f.Boards, jiratest.Board{Board: jira.Board{ID: 2, Name: "Kanban", Type: "kanban", FilterID: 200}, Filter: "project = A"})
+	srv := jiratest.NewServer(f)
+	defer srv.Close()
+
+	generate(t, burndown.Opts{Client: client(t, srv), Board: "Kanban", From: at(5, 9), To: at(7, 11), WorkdayStart: 9, WorkdayEnd: 17})
+	//The issues resolved before the time range are not searched
+	want := `filter = 200 AND (updated >= "2026-10-05 09:00" OR resolution is EMPTY)`
+	var searched []string
+	for _, r := range srv.Requests() {
+		if u, err := url.Parse(r); err == nil && strings.HasSuffix(u.Path, "/search") {
+			searched = append(searched, u.Query().Get("jql"))
+		}
+	}
+	if len(searched) != 1 || searched[0] != want {
+		t.Errorf("searches = %q, want %q", searched, want)
+	}
+}
+
+func TestBurndownBoardPages(t *testing.T) {
+	f := sprintFixture()
+	f.Boards[0].Project = "A"
+	var decoys []jiratest.Board
+	for i := 0; i < 60; i++ {
+		decoys = append(decoys, jiratest.Board{Board: jira.Board{ID: 100 + i, Name: fmt.Sprintf("Team board %d", i), Type: "scrum"}})
+	}
+	f.Boards = append(decoys, f.Boards...)
+	srv := jiratest.NewServer(f)
+	defer srv.Close()
+
+	s := generate(t, burndown.Opts{Client: client(t, srv), Board: "Team board", Sprint: "Sprint 10", WorkdayStart: 9, WorkdayEnd: 17})
+	if len(s.Series) == 0 {
+		t.Error("no burndown for the board on the second page")
+	}
+
+	f.Boards = append(f.Boards, jiratest.Board{Board: jira.Board{ID: 2, Name: "Team board", Type: "kanban"}, Project: "B"})
+	srv2 := jiratest.NewServer(f)
+	defer srv2.Close()
+	_, err := burndown.Generate(context.Background(), burndown.Opts{Client: client(t, srv2), Board: "Team board", Sprint: "Sprint 10", WorkdayStart: 9, WorkdayEnd: 17})
+	if step, ok := err.(*burndown.StepError); !ok {
+		t.Errorf("err = %v, want ambiguous board", err)
+	} else if _, ok := step.Err.(*agile.AmbiguousError); !ok {
+		t.Errorf("err = %v, want ambiguous board", err)
+	}
+	for _, filter := range []agile.BoardFilter{{Project: "A"}, {Type: "scrum"}} {
+		s = generate(t, burndown.Opts{Client: client(t, srv2), Board: "Team board", BoardFilter: filter, Sprint: "Sprint 10", WorkdayStart: 9, WorkdayEnd: 17})
+		if len(s.Series) == 0 {
+			t.Errorf("no burndown for the board filtered by %+v", filter)
+		}
+	}
+}
+
+func TestBurndownWriteFiles(t *testing.T) {
+	srv := jiratest.NewServer(sprintFixture())
+	defer srv.Close()
+	dir, err := ioutil.TempDir("", "burndown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r, err := burndown.Generate(context.Background(), burndown.Opts{Client: client(t, srv), Sprint: "10", WorkdayStart: 9, WorkdayEnd: 17, Renderer: burndown.SVG})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := r.WriteFiles(filepath.Join(dir, "report.html"), burndown.HTML, burndown.JSON, burndown.CSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 4 {
+		t.Errorf("files = %v, want html, json, csv and entries csv", files)
+	}
+	html, err := ioutil.ReadFile(filepath.Join(dir, "report.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"<svg", "Sprint 10", "Ideal", "A-2: scope added", "A-3: scope removed"} {
+		if !bytes.Contains(html, []byte(s)) {
+			t.Errorf("HTML report does not contain %q", s)
+		}
+	}
+	if bytes.Contains(html, []byte("B-1")) {
+		t.Error("HTML report contains B-1, which was never in the sprint")
+	}
+
+	csv, err := ioutil.ReadFile(filepath.Join(dir, "report.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "time,work_hours,New,In Progress,scope\n" +
+		"2026-10-05T09:00:00Z,0,10,0,0\n" +
+		"2026-10-06T10:00:00Z,9,2,8,0\n" +
+		"2026-10-06T11:00:00Z,10,6,8,4\n" +
+		"2026-10-07T09:00:00Z,16,4,8,2\n" +
+		"2026-10-07T12:00:00Z,19,4,0,2\n"
+	if string(csv) != want {
+		t.Errorf("CSV series =\n%s\nwant\n%s", csv, want)
+	}
+	entries, err := ioutil.ReadFile(filepath.Join(dir, "report-entries.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range []string{
+		"list,time,value,message\n",
+		"New,2026-10-06T11:00:00Z,4,A-2: scope added (status Open)\n",
+		"In Progress,2026-10-07T12:00:00Z,-8,A-1: change of status (from In Progress) and estimate\n",
+		"scope,2026-10-07T09:00:00Z,-2,A-3: scope removed (status Open)\n",
+	} {
+		if !bytes.Contains(entries, []byte(row)) {
+			t.Errorf("CSV entries do not contain %q:\n%s", row, entries)
+		}
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "report.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		SchemaVersion int `json:"schemaVersion"`
+		Metric        struct {
+			Name string `json:"name"`
+			Unit string `json:"unit"`
+		} `json:"metric"`
+	}
+	err = json.Unmarshal(b, &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.SchemaVersion != 2 || doc.Metric.Name != "time" || doc.Metric.Unit != "Hours" {
+		t.Errorf("JSON schema version %d and metric %+v, want 2 and time in Hours", doc.SchemaVersion, doc.Metric)
+	}
+}
+
+func TestBurnup(t *testing.T) {
+	logged := sprintFixture()
+	logged.Issues[1].Fields["timespent"] = 3600
+	logged.Issues[1].Changelog = append(logged.Issues[1].Changelog, jiratest.Change{Created: at(8, 10), Items: []jira.ChangelogItems{
+		jiratest.FieldChange("timespent", 0, 3600),
+		jiratest.FieldChange("timeestimate", 14400, 10800),
+	}})
+	tests := []struct {
+		name         string
+		fixture      jiratest.Fixture
+		spent, scope []float64
+	}{
+		{name: "sprint", fixture: sprintFixture(), spent: []float64{0, 0, 0, 6}, scope: []float64{10, 14, 12, 10}},
+		{name: "time logged on open issue", fixture: logged, spent: []float64{0, 0, 0, 6, 7}, scope: []float64{10, 14, 12, 10, 10}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := jiratest.NewServer(tt.fixture)
+			defer srv.Close()
+
+			b, err := burndown.GenerateBurnup(context.Background(), burndown.Opts{Client: client(t, srv), Sprint: "10", WorkdayStart: 9, WorkdayEnd: 17})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var s struct {
+				Series []struct {
+					Spent float64 `json:"spent"`
+					Scope float64 `json:"scope"`
+				} `json:"series"`
+			}
+			decode(t, b, &s)
+			var spent, scope []float64
+			for _, p := range s.Series {
+				spent, scope = append(spent, p.Spent), append(scope, p.Scope)
+			}
+			if !reflect.DeepEqual(spent, tt.spent) {
+				t.Errorf("spent = %v, want %v", spent, tt.spent)
+			}
+			if !reflect.DeepEqual(scope, tt.scope) {
+				t.Errorf("scope = %v, want %v", scope, tt.scope)
+			}
+		})
+	}
+}
+
+func TestVelocity(t *testing.T) {
+	closed := func() jiratest.Fixture {
+		f := sprintFixture()
+		complete := at(9, 16)
+		f.Sprints[1].State, f.Sprints[1].CompleteDate = "closed", &complete
+		return f
+	}
+	//A-1 is logged down to zero before it is done
+	logged := closed()
+	logged.Issues[0].Changelog = []jiratest.Change{
+		{Created: at(6, 10), Items: []jira.ChangelogItems{jiratest.StatusChange("Open", "In Progress")}},
+		{Created: at(7, 10), Items: []jira.ChangelogItems{jiratest.FieldChange("timeestimate", 28800, 0), jiratest.FieldChange("timespent", 0, 21600)}},
+		{Created: at(7, 12), Items: []jira.ChangelogItems{jiratest.StatusChange("In Progress", "Done")}},
+	}
+	tests := []struct {
+		name                 string
+		fixture              jiratest.Fixture
+		metric               burndown.Metric
+		committed, completed float64
+	}{
+		{name: "remaining time", fixture: closed(), metric: burndown.RemainingTime, committed: 10, completed: 8},
+		{name: "logged down before done", fixture: logged, metric: burndown.RemainingTime, committed: 10, completed: 8},
+		{name: "issue count", fixture: closed(), metric: burndown.IssueCount, committed: 2, completed: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := jiratest.NewServer(tt.fixture)
+			defer srv.Close()
+
+			v, err := burndown.GenerateVelocity(context.Background(), burndown.Opts{Client: client(t, srv), Board: "Team board", Metric: tt.metric, WorkdayStart: 9, WorkdayEnd: 17}, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var s struct {
+				Sprints []struct {
+					Sprint struct {
+						ID int `json:"id"`
+					} `json:"sprint"`
+					Committed float64 `json:"committed"`
+					Completed float64 `json:"completed"`
+				} `json:"sprints"`
+			}
+			decode(t, v, &s)
+			//Sprint 9 was never started
+			if len(s.Sprints) != 1 || s.Sprints[0].Sprint.ID != 10 {
+				t.Fatalf("sprints = %+v, want sprint 10 only", s.Sprints)
+			}
+			if p := s.Sprints[0]; p.Committed != tt.committed || p.Completed != tt.completed {
+				t.Errorf("committed %v and completed %v, want %v and %v", p.Committed, p.Completed, tt.committed, tt.completed)
+			}
+		})
+	}
+}
+
+func TestCFD(t *testing.T) {
+	tests := []struct {
+		name string
+		opts burndown.Opts
+		// last is the value of every bucket at the end
+		last map[string]float64
+		err  string
+	}{
+		//A-3 was removed from the sprint
+		{name: "issue count", opts: burndown.Opts{Metric: burndown.IssueCount}, last: map[string]float64{"New": 1, "In Progress": 0, "Done": 1}},
+		{name: "default metric", last: map[string]float64{"New": 1, "In Progress": 0, "Done": 1}},
+		//Done issues have no remaining time
+		{name: "remaining time", opts: burndown.Opts{Metric: burndown.RemainingTime}, err: "invalid metric 'time'"},
+		//The time range follows no sprint, A-3 stays in
+		{
+			name: "time range", opts: burndown.Opts{Metric: burndown.IssueCount, JQL: "project = A", From: at(5, 9), To: at(9, 17)},
+			last: map[string]float64{"New": 2, "In Progress": 0, "Done": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := jiratest.NewServer(sprintFixture())
+			defer srv.Close()
+			opts := tt.opts
+			opts.Client, opts.Board, opts.WorkdayStart, opts.WorkdayEnd = client(t, srv), "Team board", 9, 17
+
+			c, err := burndown.GenerateCFD(context.Background(), opts)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("err = %v, want %s", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var s struct {
+				Buckets []string `json:"buckets"`
+				Series  []struct {
+					Values []float64 `json:"values"`
+				} `json:"series"`
+			}
+			decode(t, c, &s)
+			if len(s.Series) == 0 {
+				t.Fatal("empty series")
+			}
+			got := make(map[string]float64)
+			for i, b := range s.Buckets {
+				got[b] = s.Series[len(s.Series)-1].Values[i]
+			}
+			if !reflect.DeepEqual(got, tt.last) {
+				t.Errorf("at the end = %v, want %v", got, tt.last)
+			}
+		})
+	}
+}
+
+func TestCycleTime(t *testing.T) {
+	f := sprintFixture()
+	f.Issues[0].Created = at(2, 9)
+	type cycle struct {
+		key                 string
+		leadTime, cycleTime float64
+	}
+	tests := []struct {
+		name string
+		opts burndown.Opts
+		want []cycle
+	}{
+		//Only A-1 was completed, created on Friday, started on Tuesday 10:00 and done on Wednesday 12:00
+		{name: "sprint", opts: burndown.Opts{Board: "Team board"}, want: []cycle{{"A-1", 27, 10}}},
+		{name: "time range", opts: burndown.Opts{Board: "Team board", JQL: "project = A", From: at(5, 9), To: at(9, 17)}, want: []cycle{{"A-1", 27, 10}}},
+		{name: "time range after completion", opts: burndown.Opts{Board: "Team board", JQL: "project = A", From: at(8, 9), To: at(9, 17)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := jiratest.NewServer(f)
+			defer srv.Close()
+			opts := tt.opts
+			opts.Client, opts.WorkdayStart, opts.WorkdayEnd = client(t, srv), 9, 17
+
+			c, err := burndown.GenerateCycleTime(context.Background(), opts, 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var s struct {
+				Issues []struct {
+					Key       string   `json:"key"`
+					LeadTime  *float64 `json:"leadTime"`
+					CycleTime *float64 `json:"cycleTime"`
+				} `json:"issues"`
+			}
+			decode(t, c, &s)
+			var got []cycle
+			for _, i := range s.Issues {
+				if i.LeadTime == nil || i.CycleTime == nil {
+					t.Fatalf("%s has lead time %v and cycle time %v, want both", i.Key, i.LeadTime, i.CycleTime)
+				}
+				got = append(got, cycle{i.Key, *i.LeadTime, *i.CycleTime})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("issues = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeInStatus(t *testing.T) {
+	type statusTime struct {
+		Status    string  `json:"status"`
+		Hours     float64 `json:"hours"`
+		WorkHours float64 `json:"workHours"`
+	}
+	type open struct {
+		issues    int
+		workHours float64
+	}
+	tests := []struct {
+		name  string
+		opts  burndown.Opts
+		times map[string][]statusTime
+		// open is the summary of the first status
+		open open
+	}{
+		{
+			//A-3 was removed from the sprint, A-2 stayed open the whole sprint
+			name: "sprint", opts: burndown.Opts{Board: "Team board"},
+			times: map[string][]statusTime{
+				"A-1": {{"Open", 25, 9}, {"In Progress", 26, 10}, {"Done", 53, 21}},
+				"A-2": {{"Open", 104, 40}},
+			},
+			open: open{2, 49},
+		},
+		{
+			name: "time range", opts: burndown.Opts{Board: "Team board", JQL: "project = A", From: at(5, 9), To: at(9, 17)},
+			times: map[string][]statusTime{
+				"A-1": {{"Open", 25, 9}, {"In Progress", 26, 10}, {"Done", 53, 21}},
+				"A-2": {{"Open", 104, 40}},
+				"A-3": {{"Open", 104, 40}},
+			},
+			open: open{3, 89},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := jiratest.NewServer(sprintFixture())
+			defer srv.Close()
+			opts := tt.opts
+			opts.Client, opts.WorkdayStart, opts.WorkdayEnd = client(t, srv), 9, 17
+
+			r, err := burndown.GenerateTimeInStatus(context.Background(), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var s struct {
+				Statuses []struct {
+					statusTime
+					Issues int `json:"issues"`
+				} `json:"statuses"`
+				Issues []struct {
+					Key   string       `json:"key"`
+					Times []statusTime `json:"times"`
+				} `json:"issues"`
+			}
+			decode(t, r, &s)
+			times := make(map[string][]statusTime)
+			for _, i := range s.Issues {
+				times[i.Key] = i.Times
+			}
+			if !reflect.DeepEqual(times, tt.times) {
+				t.Errorf("times = %v, want %v", times, tt.times)
+			}
+			if len(s.Statuses) != 3 || s.Statuses[0].Status != "Open" || (open{s.Statuses[0].Issues, s.Statuses[0].WorkHours}) != tt.open {
+				t.Errorf("statuses = %+v, want Open first with %d issues and %v work hours", s.Statuses, tt.open.issues, tt.open.workHours)
+			}
+		})
+	}
+}
+
+func TestRelease(t *testing.T) {
+	f := sprintFixture()
+	f.Versions = []jiratest.Version{
+		{ID: 20, Project: "A", Name: "1.0", Released: true, StartDate: "2026-10-05", ReleaseDate: "2026-10-09"},
+		{ID: 21, Project: "A", Name: "1.1"},
+	}
+	f.Issues[0].FixVersions = []int{20}
+	f.Issues[1].FixVersions = []int{20}
+	f.Issues[1].Changelog = []jiratest.Change{{Created: at(6, 11), Items: []jira.ChangelogItems{jiratest.FixVersionChange(0, 20)}}}
+	f.Issues[2].Changelog = []jiratest.Change{{Created: at(7, 9), Items: []jira.ChangelogItems{jiratest.FixVersionChange(20, 0)}}}
+	unreleased := sprintFixture()
+	unreleased.Versions = f.Versions
+	unreleased.Issues[0].FixVersions = []int{21}
+	unreleased.Issues[1].FixVersions = []int{21}
+	unreleased.Issues[1].Created = at(5, 12)
+
+	tests := []struct {
+		name        string
+		fixture     jiratest.Fixture
+		version     string
+		first, last []float64
+		scope       []float64
+	}{
+		//A-1 is done, A-2 was added and A-3 removed
+		{name: "released", fixture: f, version: "1.0", first: []float64{10, 0}, last: []float64{4, 0}, scope: []float64{4, -2}},
+		//The only unreleased version has no start date, it starts with the creation of A-2
+		{name: "unreleased", fixture: unreleased, first: []float64{12, 0}, last: []float64{4, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := jiratest.NewServer(tt.fixture)
+			defer srv.Close()
+
+			r, err := burndown.GenerateRelease(context.Background(), burndown.Opts{Client: client(t, srv), WorkdayStart: 9, WorkdayEnd: 17}, "A", tt.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var s struct {
+				Series []struct {
+					Values []float64 `json:"values"`
+				} `json:"series"`
+				ScopeEntries []struct {
+					Value float64 `json:"value"`
+				} `json:"scopeEntries"`
+			}
+			decode(t, r, &s)
+			if len(s.Series) == 0 {
+				t.Fatal("empty series")
+			}
+			if first := s.Series[0].Values; !reflect.DeepEqual(first, tt.first) {
+				t.Errorf("remaining at version start = %v, want %v", first, tt.first)
+			}
+			if last := s.Series[len(s.Series)-1].Values; !reflect.DeepEqual(last, tt.last) {
+				t.Errorf("remaining at the end = %v, want %v", last, tt.last)
+			}
+			var scope []float64
+			for _, e := range s.ScopeEntries {
+				scope = append(scope, e.Value)
+			}
+			if !reflect.DeepEqual(scope, tt.scope) {
+				t.Errorf("scope entries = %v, want %v", scope, tt.scope)
+			}
+		})
+	}
+}
+
+func TestEpics(t *testing.T) {
+	f := sprintFixture()
+	f.Issues[0].Epic = "A-9"
+	f.Issues[1].Parent = "A-9"
+	f.Issues = append(f.Issues,
+		jiratest.Issue{Key: "A-4", Status: "Open", Parent: "A-1", Fields: map[string]interface{}{"timeestimate": 14400}},
+		jiratest.Issue{Key: "A-5", Status: "Open", Epic: "A-9", Created: at(6, 9)},
+		jiratest.Issue{
+			Key: "A-6", Status: "Done", Epic: "A-9",
+			Fields: map[string]interface{}{"timeestimate": 0},
+			Changelog: []jiratest.Change{
+				{Created: at(6, 10), Items: []jira.ChangelogItems{jiratest.FieldChange("timeestimate", 57600, 0)}},
+				{Created: at(6, 12), Items: []jira.ChangelogItems{jiratest.StatusChange("Open", "Done")}},
+			},
+		},
+		jiratest.Issue{Key: "A-7", Status: "Done", Epic: "A-8"},
+		jiratest.Issue{Key: "A-10", Status: "Open", Epic: "A-8"},
+		jiratest.Issue{Key: "A-8", Status: "Open"},
+		jiratest.Issue{Key: "A-9", Status: "Open"},
+	)
+	tests := []struct {
+		name   string
+		metric burndown.Metric
+		keys   []string
+		// portfolio is the CSV of the portfolio, empty if the epic is not found
+		portfolio string
+	}{
+		{
+			//A-1 is done, A-2 and the sub-task A-4 of A-1 remain, A-5 has no estimate
+			//and A-6 was logged down to 0 before it was done, counting its peak estimate
+			name: "remaining time", metric: burndown.RemainingTime, keys: []string{"A-9"},
+			portfolio: "key,summary,issues,total,done,remaining,complete,without_estimate\n" +
+				"A-9,Summary of A-9,5,32,24,8,75,1\n" +
+				",Total,5,32,24,8,75,1\n",
+		},
+		{
+			name: "issue count", metric: burndown.IssueCount, keys: []string{"A-9", "A-8"},
+			portfolio: "key,summary,issues,total,done,remaining,complete,without_estimate\n" +
+				"A-9,Summary of A-9,5,5,2,3,40,0\n" +
+				"A-8,Summary of A-8,2,2,1,1,50,0\n" +
+				",Total,7,7,3,4,42.857142857142854,0\n",
+		},
+		{name: "epic not found", keys: []string{"A-9", "A-11"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := jiratest.NewServer(f)
+			defer srv.Close()
+
+			r, err := burndown.GenerateEpics(context.Background(), burndown.Opts{Client: client(t, srv), Metric: tt.metric, WorkdayStart: 9, WorkdayEnd: 17}, tt.keys)
+			if tt.portfolio == "" {
+				step, ok := err.(*burndown.StepError)
+				if !ok {
+					t.Fatalf("err = %v, want step error", err)
+				}
+				if _, ok := step.Err.(*agile.NotFoundError); !ok {
+					t.Errorf("err = %v, want epic not found", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := render(t, r, burndown.CSV); got != tt.portfolio {
+				t.Errorf("portfolio =\n%s\nwant\n%s", got, tt.portfolio)
+			}
+		})
+	}
+}
@@ -0,0 +1,403 @@
+package burndown
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	agile "reports/jira"
+	"time"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// Burnup is the collected burn-up of a sprint: the cumulative logged work against the total scope,
+// the total scope being the logged work plus the remaining estimate of the sprint issues
+type Burnup struct {
+	Sprint jira.Sprint
+	Board  agile.BoardInfo
+	opts   Opts
+	start  time.Time
+	sprint int
+	// spent holds the changes of the logged work
+	spent []entry
+	// scope holds the changes of the total scope
+	scope []entry
+	renderer
+}
+
+// GenerateBurnup collects the burn-up report of logged work for given sprint.
+// Only the sprint selection, working time and renderer of opts are used, the effort is always in hours.
+func GenerateBurnup(ctx context.Context, opts Opts) (*Burnup, error) {
+	err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+	s, err := getSprint(ctx, opts)
+	if err != nil {
+		return nil, &StepError{"resolve sprint", err}
+	}
+	b := &Burnup{Sprint: s, opts: opts, sprint: s.ID}
+	b.renderer = renderer{b}
+	if s.StartDate != nil {
+		b.start = *s.StartDate
+	}
+	if opts.StartMargin {
+		b.start = b.start.Add(-24 * time.Hour)
+	}
+	b.Board, err = opts.Client.GetBoardInfo(ctx, s.OriginBoardID)
+	if err != nil {
+		return nil, &StepError{"get board configuration", err}
+	}
+	err = searchSprintIssues(ctx, opts.Client, s, b.start, b.collector)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *Burnup) collector(member bool) func(jira.Issue) error {
+	return func(i jira.Issue) error {
+		b.collect(i, member)
+		return nil
+	}
+}
+
+// workChange is a change of the logged work, the remaining estimate or the sprint membership of an issue
+type workChange struct {
+	time            time.Time
+	spentChange     bool
+	spent           int
+	remainingChange bool
+	remaining       int
+	sprintChange    bool
+	inSprint        bool
+}
+
+func (b *Burnup) collect(i jira.Issue, member bool) {
+	changes, spent, remaining, inSprint := getWorkChanges(i, b.start, b.sprint, member)
+	if inSprint {
+		b.add(time.Time{}, spent, spent+remaining, fmt.Sprintf("%s: in sprint", i.Key))
+	}
+	for _, c := range changes {
+		newSpent, newRemaining := spent, remaining
+		if c.spentChange {
+			newSpent = c.spent
+		}
+		if c.remainingChange {
+			newRemaining = c.remaining
+		}
+		switch {
+		case c.sprintChange && c.inSprint:
+			b.add(c.time, newSpent, newSpent+newRemaining, fmt.Sprintf("%s: scope added", i.Key))
+		case c.sprintChange:
+			b.add(c.time, -spent, -spent-remaining, fmt.Sprintf("%s: scope removed", i.Key))
+		case inSprint && c.spentChange:
+			b.add(c.time, newSpent-spent, newSpent+newRemaining-spent-remaining, fmt.Sprintf("%s: logged work", i.Key))
+		case inSprint:
+			b.add(c.time, 0, newRemaining-remaining, fmt.Sprintf("%s: changed estimate", i.Key))
+		}
+		spent, remaining = newSpent, newRemaining
+		if c.sprintChange {
+			inSprint = c.inSprint
+		}
+	}
+}
+
+// getWorkChanges gives the changes after t and the logged work, remaining estimate and sprint membership before them
+func getWorkChanges(i jira.Issue, t time.Time, sprint int, member bool) (changes []workChange, spent, remaining int, inSprint bool) {
+	spent, remaining, inSprint = -1, -1, member
+	sprintFound := false
+	for _, h := range i.Changelog.Histories {
+		created, err := h.CreatedTime()
+		if err != nil {
+			log.Printf("Could not parse time from %s, ignoring history entry %v (by %v)", h.Created, h.Id, h.Author)
+			continue
+		}
+		if created.Before(t) {
+			continue
+		}
+		c := workChange{time: created}
+		for _, it := range h.Items {
+			switch it.Field {
+			case "timespent":
+				c.spentChange, c.spent = true, parseInt(it.ToString)
+				if spent == -1 {
+					spent = parseInt(it.FromString)
+				}
+			case "timeestimate":
+				c.remainingChange, c.remaining = true, parseInt(it.ToString)
+				if remaining == -1 {
+					remaining = parseInt(it.FromString)
+				}
+			case "Sprint":
+				was := containsSprint(it.From, sprint)
+				c.inSprint = containsSprint(it.To, sprint)
+				c.sprintChange = was != c.inSprint
+				if c.sprintChange && !sprintFound {
+					inSprint, sprintFound = was, true
+				}
+			}
+		}
+		if c.spentChange || c.remainingChange || c.sprintChange {
+			changes = append(changes, c)
+		}
+	}
+	if spent == -1 {
+		spent = i.Fields.TimeSpent
+	}
+	if remaining == -1 {
+		remaining = i.Fields.TimeEstimate
+	}
+	return
+}
+
+func (b *Burnup) add(t time.Time, spent, scope int, msg string) {
+	if spent != 0 {
+		b.spent = append(b.spent, entry{t, spent, msg})
+	}
+	if scope != 0 {
+		b.scope = append(b.scope, entry{t, scope, msg})
+	}
+}
+
+// series gives the logged work and the total scope after each change.
+// The single remaining value of the entries holds the logged work.
+func (b *Burnup) series() []tableEntry {
+	d := data{buckets: [][]entry{b.spent}, scope: b.scope}
+	return d.collapse(b.start)
+}
+
+// workHours tells if the chart is drawn in sprint work hours
+func (b *Burnup) workHours() bool {
+	return !b.opts.FullTimeline && b.Sprint.StartDate != nil
+}
+
+func (b *Burnup) converter() converter {
+	c := converter{
+		BoardInfo:         b.Board,
+		WorkDayStartHours: b.opts.WorkdayStart,
+		WorkDayEndHours:   b.opts.WorkdayEnd,
+	}
+	if b.Sprint.StartDate != nil {
+		c.Start = *b.Sprint.StartDate
+	}
+	return c
+}
+
+// burnupDiagram is the burn-up chart, X is in seconds since epoch on the full timeline or in sprint work hours
+type burnupDiagram struct {
+	Sprint    jira.Sprint
+	Dates     bool
+	Points    []burnupPoint
+	Markers   []svgMarker
+	WorkHours float64
+}
+type burnupPoint struct {
+	X, Spent, Scope float64
+}
+
+func (b *Burnup) prepareDiagram() burnupDiagram {
+	d := burnupDiagram{Sprint: b.Sprint, Dates: !b.workHours()}
+	conv := b.converter()
+	x := func(t time.Time) float64 {
+		if d.Dates {
+			return float64(t.Unix())
+		}
+		return conv.toSprintWorkTime(conv.Start, t).Hours()
+	}
+	for _, e := range b.series() {
+		if e.Time.IsZero() {
+			continue
+		}
+		d.Points = append(d.Points, burnupPoint{x(e.Time), float64(e.Remaining[0]) / 3600, float64(e.Scope) / 3600})
+	}
+	if b.Sprint.StartDate != nil {
+		d.Markers = append(d.Markers, svgMarker{x(*b.Sprint.StartDate), "Sprint start"})
+	}
+	if b.Sprint.EndDate != nil {
+		d.Markers = append(d.Markers, svgMarker{x(*b.Sprint.EndDate), "Sprint end"})
+		d.WorkHours = conv.toSprintWorkTime(conv.Start, *b.Sprint.EndDate).Hours()
+	}
+	return d
+}
+
+func (b *Burnup) writeHTML(w io.Writer) error {
+	d := b.prepareDiagram()
+	var err error
+	if b.opts.Renderer == SVG {
+		err = d.printSVG(w)
+	} else {
+		err = printHeader(w)
+		if err != nil {
+			return err
+		}
+		err = d.printDiagram(w)
+	}
+	if err != nil {
+		return err
+	}
+	hours := estimator{unit: 3600, name: "Hours"}
+	err = printTable(w, "Logged work", b.spent, hours)
+	if err != nil {
+		return err
+	}
+	return printTable(w, "Total scope", b.scope, hours)
+}
+
+func (d burnupDiagram) printDiagram(w io.Writer) error {
+	t := `
+	   <div id="burnup" style="width: 100%; height: 500px;"></div>
+	   <p>Sprint start: {{ .Sprint.StartDate }}</p>
+	   <p>Sprint end: {{ .Sprint.EndDate }}</p>
+	   {{ if not .Dates }}<p>Total hours in Sprint: {{ .WorkHours }}</p>{{ end }}
+	   Generated: {{now}}
+	<script>
+	google.charts.load('current', {'packages':['corechart']});
+	google.charts.setOnLoadCallback(drawChart);
+
+	function drawChart() {
+	var data = new google.visualization.DataTable();
+	data.addColumn({{ if .Dates }}'datetime'{{ else }}'number'{{ end }}, 'Time');
+	data.addColumn({type:'string', role:'annotation'});
+	data.addColumn('number', 'Logged work');
+	data.addColumn('number', 'Total scope');
+	data.addRows([
+		{{ range .Points }}
+		[{{ x .X }}, null, {{ .Spent }}, {{ .Scope }}],{{ end }}
+		{{ range .Markers }}[{{ x .X }}, {{ .Label }}, null, null],{{ end }}
+		[null, null, null, null]
+	]);
+
+		var options = {
+			title: 'Sprint Burn-up (logged work) - {{ .Sprint.Name }}',
+			hAxis: {title: {{ if .Dates }}'Days'{{ else }}'Sprint work hours'{{ end }},  titleTextStyle: {color: '#333'}},
+			vAxis: {title: 'Hours', minValue: 0},
+			interpolateNulls: true,
+			annotations: {style:'line'}
+		};
+
+		var chart = new google.visualization.LineChart(document.getElementById('burnup'));
+		chart.draw(data, options);
+	}
+	</script>`
+	tpl, err := template.New("t").Funcs(template.FuncMap{
+		"now": time.Now,
+		"x": func(x float64) template.JS {
+			if d.Dates {
+				return template.JS(fmt.Sprintf("new Date(%d)", int64(x)*1000))
+			}
+			return template.JS(formatFloat(x))
+		},
+	}).Parse(t)
+	if err != nil {
+		return err
+	}
+	return tpl.Execute(w, d)
+}
+
+func (d burnupDiagram) printSVG(w io.Writer) error {
+	c := svgChart{
+		Title:   "Sprint Burn-up (logged work) - " + d.Sprint.Name,
+		XTitle:  "Sprint work hours",
+		YTitle:  "Hours",
+		XTicks:  numberTicks,
+		Lines:   []svgSeries{{Name: "Logged work"}, {Name: "Total scope"}},
+		Markers: d.Markers,
+	}
+	if d.Dates {
+		c.XTitle, c.XTicks = "Days", dateTicks
+	}
+	for _, p := range d.Points {
+		c.X = append(c.X, p.X)
+		c.Lines[0].Values = append(c.Lines[0].Values, p.Spent)
+		c.Lines[1].Values = append(c.Lines[1].Values, p.Scope)
+	}
+	t := `
+	   <div id="burnup" style="width: 100%;">{{ chart }}</div>
+	   <p>Sprint start: {{ .Sprint.StartDate }}</p>
+	   <p>Sprint end: {{ .Sprint.EndDate }}</p>
+	   {{ if not .Dates }}<p>Total hours in Sprint: {{ .WorkHours }}</p>{{ end }}
+	   Generated: {{now}}`
+	return printSVGPage(w, t, c, template.FuncMap{}, d)
+}
+
+// burnupExport is the JSON document of the burn-up, all effort values are in hours
+type burnupExport struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	Generated     time.Time      `json:"generated"`
+	Sprint        exportSprint   `json:"sprint"`
+	WorkingTime   exportWorkTime `json:"workingTime"`
+	// Series is the logged work and total scope after each change, as drawn in the chart
+	Series []burnupExportPoint `json:"series"`
+	// Entries are the individual changes of the logged work
+	Entries []exportEntry `json:"entries"`
+	// ScopeEntries are the individual changes of the total scope
+	ScopeEntries []exportEntry `json:"scopeEntries"`
+}
+type burnupExportPoint struct {
+	// Time is null for the state before the report start
+	Time *time.Time `json:"time"`
+	// WorkHours is the sprint work hours from sprint start, only in work hours mode
+	WorkHours *float64 `json:"workHours,omitempty"`
+	Spent     float64  `json:"spent"`
+	Scope     float64  `json:"scope"`
+}
+
+func (b *Burnup) export() exporter {
+	hours := data{estimate: estimator{unit: 3600, name: "Hours"}}
+	e := burnupExport{
+		SchemaVersion: schemaVersion,
+		Generated:     time.Now(),
+		Sprint:        exportSprintOf(b.Sprint),
+		WorkingTime:   exportWorkTimeOf(b.opts, b.Board, b.start),
+		Series:        []burnupExportPoint{},
+		Entries:       hours.exportEntries("", b.spent),
+		ScopeEntries:  hours.exportEntries("", b.scope),
+	}
+	conv := b.converter()
+	for _, v := range b.series() {
+		p := burnupExportPoint{
+			Time:  exportTime(v.Time),
+			Spent: hours.estimate.display(v.Remaining[0]),
+			Scope: hours.estimate.display(v.Scope),
+		}
+		if b.workHours() && p.Time != nil {
+			h := conv.toSprintWorkTime(conv.Start, v.Time).Hours()
+			p.WorkHours = &h
+		}
+		e.Series = append(e.Series, p)
+	}
+	return e
+}
+
+// writeCSV writes the series with columns: time, work_hours, spent, scope
+func (e burnupExport) writeCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"time", "work_hours", "spent", "scope"})
+	for _, p := range e.Series {
+		var workHours string
+		if p.WorkHours != nil {
+			workHours = formatFloat(*p.WorkHours)
+		}
+		c.Write([]string{csvTime(p.Time), workHours, formatFloat(p.Spent), formatFloat(p.Scope)})
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// writeEntriesCSV writes the entries with columns: list ('spent' or 'scope'), time, value, message
+func (e burnupExport) writeEntriesCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"list", "time", "value", "message"})
+	for _, v := range e.Entries {
+		c.Write([]string{"spent", csvTime(v.Time), formatFloat(v.Value), v.Message})
+	}
+	for _, v := range e.ScopeEntries {
+		c.Write([]string{"scope", csvTime(v.Time), formatFloat(v.Value), v.Message})
+	}
+	c.Flush()
+	return c.Error()
+}
@@ -0,0 +1,370 @@
+package burndown
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	agile "reports/jira"
+	"time"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// CFD is the cumulative flow of a sprint or of the issues matching a JQL query:
+// the effort in every status bucket, including done, over time
+type CFD struct {
+	// Sprint is the reported sprint, or a sprint without ID spanning the time range
+	Sprint jira.Sprint
+	Board  agile.BoardInfo
+	opts   Opts
+	end    time.Time
+	data   *data
+	renderer
+}
+
+// GenerateCFD collects the cumulative flow of the sprint or the time range of opts.
+// The metric defaults to IssueCount, RemainingTime is not supported.
+func GenerateCFD(ctx context.Context, opts Opts) (*CFD, error) {
+	switch opts.Metric {
+	case "":
+		opts.Metric = IssueCount
+	case RemainingTime:
+		//Done issues have no remaining time, the done band would always be empty
+		return nil, &OptionError{"metric", opts.Metric, oneOf(StoryPoints, IssueCount)}
+	}
+	err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+	c := &CFD{}
+	c.renderer = renderer{c}
+	sc, err := opts.scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts.JQL = sc.jql
+	c.Sprint, c.Board, c.opts, c.end = sc.sprint, sc.board, opts, sc.end
+	mapping, err := opts.flowMapping(ctx, c.Board)
+	if err != nil {
+		return nil, err
+	}
+	c.data = &data{start: *c.Sprint.StartDate, statuses: mapping.classifier()}
+	if sc.jql == "" {
+		c.data.in = sprintContainer(c.Sprint.ID)
+	}
+	c.data.buckets = make([][]entry, len(c.data.statuses.names))
+	c.data.estimate, err = newEstimator(opts.Metric, c.Board)
+	if err != nil {
+		return nil, &StepError{"select metric", err}
+	}
+	c.data.fromCreation = sc.jql != ""
+	err = opts.searchScope(ctx, sc, c.data.collector)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// flowMapping gives the status mapping of the options with the done statuses as the last bucket,
+// so the issues are counted in every status
+func (opts Opts) flowMapping(ctx context.Context, bi agile.BoardInfo) (*StatusMapping, error) {
+	m, err := opts.statusMapping(ctx, bi)
+	if err != nil {
+		return nil, err
+	}
+	done := "Done"
+	if opts.BoardColumns {
+		for i := len(bi.Columns) - 1; i >= 0; i-- {
+			if len(bi.Columns[i].Statuses) > 0 {
+				done = bi.Columns[i].Name
+				break
+			}
+		}
+	}
+	buckets := append(append([]Bucket(nil), m.Buckets...), Bucket{done, m.Done})
+	return &StatusMapping{Buckets: buckets}, nil
+}
+
+// workHours tells if the chart is drawn in work hours from the start
+func (c *CFD) workHours() bool {
+	return !c.opts.FullTimeline
+}
+
+func (c *CFD) converter() converter {
+	return workConverter(c.opts, c.Board, c.data.start)
+}
+
+// series gives the effort in each bucket after each change from start to end,
+// ending with the state at the end
+func (d *data) series(end time.Time) []tableEntry {
+	var series []tableEntry
+	for _, e := range d.collapse(d.start) {
+		if e.Time.After(end) {
+			break
+		}
+		if e.Time.Before(d.start) {
+			e.Time = d.start
+		}
+		if len(series) > 0 && series[len(series)-1].Time.Equal(e.Time) {
+			series[len(series)-1] = e
+			continue
+		}
+		series = append(series, e)
+	}
+	if len(series) > 0 && series[len(series)-1].Time.Before(end) {
+		last := series[len(series)-1]
+		last.Time = end
+		series = append(series, last)
+	}
+	return series
+}
+
+// cfdDiagram is the stacked chart, X is in seconds since epoch on the full timeline or in work hours
+type cfdDiagram struct {
+	// ID is the element ID of the chart, unique within the page
+	ID    string
+	Title string
+	Dates bool
+	Unit  string
+	// Buckets are in stacking order, done at the bottom
+	Buckets []string
+	Points  []cfdPoint
+	Markers []svgMarker
+}
+type cfdPoint struct {
+	X float64
+	// Values are in the order of the buckets
+	Values []float64
+}
+
+func (c *CFD) prepareDiagram() cfdDiagram {
+	d, x := c.data.stackedDiagram("Cumulative flow - "+c.Sprint.Name, c.end, c.converter(), !c.workHours())
+	if c.opts.JQL == "" {
+		d.Markers = append(d.Markers, svgMarker{x(*c.Sprint.StartDate), "Sprint start"})
+		if c.Sprint.EndDate != nil && !c.Sprint.EndDate.After(c.end) {
+			d.Markers = append(d.Markers, svgMarker{x(*c.Sprint.EndDate), "Sprint end"})
+		}
+	}
+	return d
+}
+
+// stackedDiagram gives the stacked chart of the buckets until end, last bucket at the bottom,
+// with the X value of a time for markers
+func (d *data) stackedDiagram(title string, end time.Time, conv converter, dates bool) (cfdDiagram, func(time.Time) float64) {
+	diag := cfdDiagram{ID: "cfd", Title: title, Dates: dates, Unit: d.estimate.name}
+	names := d.statuses.names
+	for i := len(names) - 1; i >= 0; i-- {
+		diag.Buckets = append(diag.Buckets, names[i])
+	}
+	x := func(t time.Time) float64 {
+		if dates {
+			return float64(t.Unix())
+		}
+		return conv.toSprintWorkTime(conv.Start, t).Hours()
+	}
+	for _, e := range d.series(end) {
+		p := cfdPoint{X: x(e.Time)}
+		for i := len(e.Remaining) - 1; i >= 0; i-- {
+			p.Values = append(p.Values, d.estimate.display(e.Remaining[i]))
+		}
+		if n := len(diag.Points); n > 0 && diag.Points[n-1].X == p.X {
+			//Changes outside working time collapse to the same work hour
+			diag.Points[n-1] = p
+			continue
+		}
+		diag.Points = append(diag.Points, p)
+	}
+	return diag, x
+}
+
+func (c *CFD) writeHTML(w io.Writer) error {
+	d := c.prepareDiagram()
+	var err error
+	if c.opts.Renderer == SVG {
+		err = d.printSVG(w)
+	} else {
+		err = printHeader(w)
+		if err != nil {
+			return err
+		}
+		err = d.printDiagram(w)
+	}
+	if err != nil {
+		return err
+	}
+	err = executeTemplate(w, `
+	<p>From: {{ .From }}</p>
+	<p>To: {{ .To }}</p>
+	Generated: {{ now }}`, struct{ From, To time.Time }{c.data.start, c.end})
+	if err != nil {
+		return err
+	}
+	for i, name := range c.data.statuses.names {
+		err = printTable(w, name, c.data.buckets[i], c.data.estimate)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d cfdDiagram) printDiagram(w io.Writer) error {
+	t := `
+	<div id="{{ .ID }}" style="width: 100%; height: 500px;"></div>
+	<script>
+	google.charts.load('current', {'packages':['corechart']});
+	google.charts.setOnLoadCallback(function() {
+	var data = new google.visualization.DataTable();
+	data.addColumn({{ if .Dates }}'datetime'{{ else }}'number'{{ end }}, 'Time');
+	data.addColumn({type:'string', role:'annotation'});
+	{{ range .Buckets }}data.addColumn('number', {{ . }});
+	{{ end }}data.addRows([
+		{{ range .Points }}
+		[{{ x .X }}, null{{ range .Values }}, {{ . }}{{ end }}],{{ end }}
+		{{ range .Markers }}[{{ x .X }}, {{ .Label }}{{ range $.Buckets }}, null{{ end }}],{{ end }}
+	]);
+
+		var options = {
+			title: {{ .Title }},
+			hAxis: {title: {{ if .Dates }}'Days'{{ else }}'Work hours'{{ end }},  titleTextStyle: {color: '#333'}},
+			vAxis: {title: {{ .Unit }}, minValue: 0},
+			isStacked: true,
+			annotations: {style:'line'}
+		};
+
+		var chart = new google.visualization.AreaChart(document.getElementById({{ .ID }}));
+		chart.draw(data, options);
+	});
+	</script>`
+	tpl, err := template.New("t").Funcs(template.FuncMap{
+		"x": func(x float64) template.JS {
+			if d.Dates {
+				return template.JS(fmt.Sprintf("new Date(%d)", int64(x)*1000))
+			}
+			return template.JS(formatFloat(x))
+		},
+	}).Parse(t)
+	if err != nil {
+		return err
+	}
+	return tpl.Execute(w, d)
+}
+
+func (d cfdDiagram) printSVG(w io.Writer) error {
+	c := svgChart{
+		Title:   d.Title,
+		XTitle:  "Work hours",
+		YTitle:  d.Unit,
+		XTicks:  numberTicks,
+		Stacked: newStackedSeries(d.Buckets),
+		Markers: d.Markers,
+	}
+	if d.Dates {
+		c.XTitle, c.XTicks = "Days", dateTicks
+	}
+	for _, p := range d.Points {
+		c.X = append(c.X, p.X)
+		for i, v := range p.Values {
+			c.Stacked[i].Values = append(c.Stacked[i].Values, v)
+		}
+	}
+	return printSVGPage(w, `<div id="{{ .ID }}" style="width: 100%;">{{ chart }}</div>`, c, template.FuncMap{}, d)
+}
+
+// cfdExport is the JSON document of the cumulative flow, effort values are in the unit of the metric
+type cfdExport struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	Generated     time.Time `json:"generated"`
+	// Sprint is null for a time range
+	Sprint      *exportSprint  `json:"sprint"`
+	JQL         string         `json:"jql,omitempty"`
+	From        time.Time      `json:"from"`
+	To          time.Time      `json:"to"`
+	Metric      exportMetric   `json:"metric"`
+	WorkingTime exportWorkTime `json:"workingTime"`
+	// Buckets are the names of the status buckets in workflow order, done last
+	Buckets []string `json:"buckets"`
+	// Series is the effort in each bucket after each change
+	Series []cfdExportPoint `json:"series"`
+	// Entries are the individual changes of the buckets making up the series
+	Entries []exportEntry `json:"entries"`
+}
+type cfdExportPoint struct {
+	Time time.Time `json:"time"`
+	// WorkHours is the work hours from the start, only in work hours mode
+	WorkHours *float64 `json:"workHours,omitempty"`
+	// Values are the effort of each bucket, in the order of buckets
+	Values []float64 `json:"values"`
+}
+
+func (c *CFD) export() exporter {
+	metric := c.opts.Metric
+	if metric == "" {
+		metric = RemainingTime
+	}
+	d := c.data
+	e := cfdExport{
+		SchemaVersion: schemaVersion,
+		Generated:     time.Now(),
+		JQL:           c.opts.JQL,
+		From:          d.start,
+		To:            c.end,
+		Metric:        exportMetric{metric, d.estimate.name},
+		WorkingTime:   exportWorkTimeOf(c.opts, c.Board, d.start),
+		Buckets:       d.statuses.names,
+		Series:        []cfdExportPoint{},
+		Entries:       []exportEntry{},
+	}
+	if c.opts.JQL == "" {
+		s := exportSprintOf(c.Sprint)
+		e.Sprint = &s
+	}
+	for i, name := range d.statuses.names {
+		e.Entries = append(e.Entries, d.exportEntries(name, d.buckets[i])...)
+	}
+	conv := c.converter()
+	for _, v := range c.data.series(c.end) {
+		p := cfdExportPoint{Time: v.Time}
+		for _, r := range v.Remaining {
+			p.Values = append(p.Values, d.estimate.display(r))
+		}
+		if c.workHours() {
+			h := conv.toSprintWorkTime(conv.Start, v.Time).Hours()
+			p.WorkHours = &h
+		}
+		e.Series = append(e.Series, p)
+	}
+	return e
+}
+
+// writeCSV writes the series with columns: time, work_hours, one column per bucket
+func (e cfdExport) writeCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	c.Write(append([]string{"time", "work_hours"}, e.Buckets...))
+	for _, p := range e.Series {
+		var workHours string
+		if p.WorkHours != nil {
+			workHours = formatFloat(*p.WorkHours)
+		}
+		row := []string{csvTime(&p.Time), workHours}
+		for _, v := range p.Values {
+			row = append(row, formatFloat(v))
+		}
+		c.Write(row)
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// writeEntriesCSV writes the entries with columns: list (bucket name), time, value, message
+func (e cfdExport) writeEntriesCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"list", "time", "value", "message"})
+	for _, v := range e.Entries {
+		c.Write([]string{v.Bucket, csvTime(v.Time), formatFloat(v.Value), v.Message})
+	}
+	c.Flush()
+	return c.Error()
+}
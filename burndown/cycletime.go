@@ -0,0 +1,488 @@
+package burndown
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	agile "reports/jira"
+	"sort"
+	"time"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// percentiles are the percentiles of the lead and cycle times drawn and reported
+var percentiles = []int{50, 85, 95}
+
+// CycleTime is the lead and cycle time of the issues completed in a sprint or in the time range of a JQL query.
+// Lead time is from creation to done, cycle time from the first move to an in progress status to done.
+// The first status bucket is waiting, the later buckets are in progress.
+// Both are in work hours, or in days with the full timeline.
+type CycleTime struct {
+	// Sprint is the reported sprint, or a sprint without ID spanning the time range
+	Sprint   jira.Sprint
+	Board    agile.BoardInfo
+	opts     Opts
+	window   int
+	end      time.Time
+	statuses classifier
+	// issues are the completed issues, by completion
+	issues []issueTimes
+	renderer
+}
+
+// issueTimes is the life of a completed issue, created and started are zero if unknown
+type issueTimes struct {
+	key                    string
+	created, started, done time.Time
+	transitions            []transition
+}
+type transition struct {
+	time     time.Time
+	from, to string
+}
+
+// statusTransitions gives the status changes in the whole changelog of the issue, oldest first
+func statusTransitions(i jira.Issue) []transition {
+	var result []transition
+	for _, c := range getStatusAndEstimateChanges(i.Changelog.Histories, time.Time{}, estimator{}, container{}) {
+		if c.statusChange {
+			result = append(result, transition{c.time, c.oldStatus, c.newStatus})
+		}
+	}
+	return result
+}
+
+// GenerateCycleTime collects the lead and cycle times of the issues completed in the sprint or the time range
+// of opts. The rolling average is taken over the cycle times of the last window issues.
+func GenerateCycleTime(ctx context.Context, opts Opts, window int) (*CycleTime, error) {
+	err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+	if window < 1 {
+		return nil, &OptionError{"rolling average window", window, "at least 1"}
+	}
+	c := &CycleTime{window: window}
+	c.renderer = renderer{c}
+	sc, err := opts.scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts.JQL = sc.jql
+	c.Sprint, c.Board, c.opts, c.end = sc.sprint, sc.board, opts, sc.end
+	mapping, err := opts.statusMapping(ctx, c.Board)
+	if err != nil {
+		return nil, err
+	}
+	c.statuses = mapping.classifier()
+	err = opts.searchScope(ctx, sc, members(c.collect))
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(c.issues, func(i, j int) bool {
+		return c.issues[i].done.Before(c.issues[j].done)
+	})
+	return c, nil
+}
+
+func (c *CycleTime) collect(i jira.Issue) {
+	t := issueTimes{key: i.Key, created: time.Time(i.Fields.Created), transitions: statusTransitions(i)}
+	for _, tr := range t.transitions {
+		b := c.statuses.bucketOf(tr.to)
+		if b > 0 && t.started.IsZero() {
+			t.started = tr.time
+		}
+		if b < 0 && c.statuses.bucketOf(tr.from) >= 0 {
+			t.done = tr.time
+		}
+	}
+	if c.statuses.bucketOf(i.Fields.Status.Name) >= 0 || t.done.Before(*c.Sprint.StartDate) || t.done.After(c.end) {
+		//Not done, or not completed during the report
+		return
+	}
+	c.issues = append(c.issues, t)
+}
+
+// workHours tells if the times are in work hours
+func (c *CycleTime) workHours() bool {
+	return !c.opts.FullTimeline
+}
+
+func (c *CycleTime) unit() string {
+	if c.workHours() {
+		return "Work hours"
+	}
+	return "Days"
+}
+
+// duration gives the time from - to in the unit, nil if from is unknown
+func (c *CycleTime) duration(from, to time.Time) *float64 {
+	if from.IsZero() {
+		return nil
+	}
+	d := to.Sub(from).Hours() / 24
+	if c.workHours() {
+		d = workConverter(c.opts, c.Board, from).toSprintWorkTime(from, to).Hours()
+	}
+	return &d
+}
+
+type cycleRow struct {
+	Key                    string
+	Created, Started, Done time.Time
+	Lead, Cycle            *float64
+	// Average is the rolling average cycle time up to this issue, nil without cycle time
+	Average *float64
+}
+
+func (c *CycleTime) rows() []cycleRow {
+	var rows []cycleRow
+	var cycles []float64
+	for _, t := range c.issues {
+		r := cycleRow{
+			Key:     t.key,
+			Created: t.created,
+			Started: t.started,
+			Done:    t.done,
+			Lead:    c.duration(t.created, t.done),
+			Cycle:   c.duration(t.started, t.done),
+		}
+		if r.Cycle != nil {
+			cycles = append(cycles, *r.Cycle)
+			last := cycles
+			if len(last) > c.window {
+				last = last[len(last)-c.window:]
+			}
+			avg := mean(last)
+			r.Average = &avg
+		}
+		rows = append(rows, r)
+	}
+	return rows
+}
+
+type cyclePercentile struct {
+	Percentile  int
+	Lead, Cycle *float64
+}
+
+func cyclePercentiles(rows []cycleRow) []cyclePercentile {
+	var leads, cycles []float64
+	for _, r := range rows {
+		if r.Lead != nil {
+			leads = append(leads, *r.Lead)
+		}
+		if r.Cycle != nil {
+			cycles = append(cycles, *r.Cycle)
+		}
+	}
+	var result []cyclePercentile
+	for _, p := range percentiles {
+		result = append(result, cyclePercentile{p, percentile(leads, p), percentile(cycles, p)})
+	}
+	return result
+}
+
+// percentile gives the nearest rank percentile of the values, nil without values
+func percentile(values []float64, p int) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := int(math.Ceil(float64(p) / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return &sorted[rank-1]
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// cycleDiagram is the control chart of the cycle times, X is in seconds since epoch
+type cycleDiagram struct {
+	Title  string
+	Unit   string
+	Window int
+	Points []cyclePoint
+	// Percentiles are the cycle time percentiles drawn as horizontal lines
+	Percentiles []cycleLine
+}
+type cyclePoint struct {
+	X              float64
+	Key            string
+	Cycle, Average float64
+}
+type cycleLine struct {
+	Label string
+	Value float64
+}
+
+func (c *CycleTime) prepareDiagram(rows []cycleRow, ps []cyclePercentile) cycleDiagram {
+	d := cycleDiagram{Title: "Cycle time - " + c.Sprint.Name, Unit: c.unit(), Window: c.window}
+	for _, r := range rows {
+		if r.Cycle != nil {
+			d.Points = append(d.Points, cyclePoint{float64(r.Done.Unix()), r.Key, *r.Cycle, *r.Average})
+		}
+	}
+	for _, p := range ps {
+		if p.Cycle != nil {
+			d.Percentiles = append(d.Percentiles, cycleLine{fmt.Sprintf("%dth percentile", p.Percentile), *p.Cycle})
+		}
+	}
+	return d
+}
+
+func (c *CycleTime) writeHTML(w io.Writer) error {
+	rows := c.rows()
+	ps := cyclePercentiles(rows)
+	d := c.prepareDiagram(rows, ps)
+	var err error
+	if c.opts.Renderer == SVG {
+		err = d.printSVG(w)
+	} else {
+		err = printHeader(w)
+		if err != nil {
+			return err
+		}
+		err = d.printDiagram(w)
+	}
+	if err != nil {
+		return err
+	}
+	tpl, err := template.New("t").Funcs(template.FuncMap{
+		"now": time.Now,
+		"value": func(v *float64) string {
+			if v == nil {
+				return "-"
+			}
+			return fmt.Sprintf("%.1f", *v)
+		},
+		"time": func(t time.Time) string {
+			if t.IsZero() {
+				return "-"
+			}
+			return t.String()
+		},
+	}).Parse(`
+	<p>From: {{ .From }}</p>
+	<p>To: {{ .To }}</p>
+	<h2>Percentiles</h2>
+	<table>
+	<tr><th>Percentile</th><th>Lead time ({{ .Unit }})</th><th>Cycle time ({{ .Unit }})</th></tr>
+	{{ range .Percentiles }}
+	<tr><td>{{ .Percentile }}</td><td>{{ value .Lead }}</td><td>{{ value .Cycle }}</td></tr>{{ end }}
+	</table>
+	<h2>Completed issues</h2>
+	<table>
+	<tr><th>Issue</th><th>Created</th><th>Started</th><th>Done</th><th>Lead time</th><th>Cycle time</th><th>Rolling average</th></tr>
+	{{ range .Rows }}
+	<tr><td>{{ .Key }}</td><td>{{ time .Created }}</td><td>{{ time .Started }}</td><td>{{ time .Done }}</td><td>{{ value .Lead }}</td><td>{{ value .Cycle }}</td><td>{{ value .Average }}</td></tr>{{ end }}
+	</table>
+	Generated: {{ now }}`)
+	if err != nil {
+		return err
+	}
+	return tpl.Execute(w, struct {
+		From, To    time.Time
+		Unit        string
+		Percentiles []cyclePercentile
+		Rows        []cycleRow
+	}{*c.Sprint.StartDate, c.end, c.unit(), ps, rows})
+}
+
+func (d cycleDiagram) printDiagram(w io.Writer) error {
+	t := `
+	<div id="cycletime" style="width: 100%; height: 500px;"></div>
+	<script>
+	google.charts.load('current', {'packages':['corechart']});
+	google.charts.setOnLoadCallback(drawChart);
+
+	function drawChart() {
+	var data = new google.visualization.DataTable();
+	data.addColumn('datetime', 'Done');
+	data.addColumn('number', 'Cycle time');
+	data.addColumn({type:'string', role:'tooltip'});
+	data.addColumn('number', {{ printf "Rolling average (%d issues)" .Window }});
+	{{ range .Percentiles }}data.addColumn('number', {{ .Label }});
+	{{ end }}data.addRows([
+		{{ range .Points }}
+		[{{ x .X }}, {{ .Cycle }}, {{ printf "%s: %.1f" .Key .Cycle }}, {{ .Average }}{{ range $.Percentiles }}, {{ .Value }}{{ end }}],{{ end }}
+	]);
+
+		var options = {
+			title: {{ .Title }},
+			hAxis: {title: 'Done',  titleTextStyle: {color: '#333'}},
+			vAxis: {title: {{ .Unit }}, minValue: 0},
+			seriesType: 'line',
+			series: {0: {type: 'scatter'}{{ range $i, $p := .Percentiles }}, {{ add $i 2 }}: {lineDashStyle: [4, 4]}{{ end }}}
+		};
+
+		var chart = new google.visualization.ComboChart(document.getElementById('cycletime'));
+		chart.draw(data, options);
+	}
+	</script>`
+	tpl, err := template.New("t").Funcs(template.FuncMap{
+		"x": func(x float64) template.JS {
+			return template.JS(fmt.Sprintf("new Date(%d)", int64(x)*1000))
+		},
+		"add": func(a, b int) int {
+			return a + b
+		},
+	}).Parse(t)
+	if err != nil {
+		return err
+	}
+	return tpl.Execute(w, d)
+}
+
+func (d cycleDiagram) printSVG(w io.Writer) error {
+	c := svgChart{
+		Title:  d.Title,
+		XTitle: "Done",
+		YTitle: d.Unit,
+		XTicks: dateTicks,
+		Lines:  []svgSeries{{Name: fmt.Sprintf("Rolling average (%d issues)", d.Window)}},
+		Points: []svgSeries{{Name: "Cycle time"}},
+	}
+	for _, p := range d.Points {
+		c.X = append(c.X, p.X)
+		c.Lines[0].Values = append(c.Lines[0].Values, p.Average)
+		c.Points[0].Values = append(c.Points[0].Values, p.Cycle)
+		c.Points[0].Labels = append(c.Points[0].Labels, fmt.Sprintf("%s: %.1f", p.Key, p.Cycle))
+	}
+	if len(c.X) > 0 {
+		for _, p := range d.Percentiles {
+			c.Lines = append(c.Lines, svgSeries{
+				Name:   p.Label,
+				X:      []float64{c.X[0], c.X[len(c.X)-1]},
+				Values: []float64{p.Value, p.Value},
+				Dashed: true,
+			})
+		}
+	}
+	return printSVGPage(w, `<div id="cycletime" style="width: 100%;">{{ chart }}</div>`, c, template.FuncMap{}, d)
+}
+
+// cycleTimeExport is the JSON document of the lead and cycle times, in the unit of the report
+type cycleTimeExport struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	Generated     time.Time `json:"generated"`
+	// Sprint is null for a time range
+	Sprint *exportSprint `json:"sprint"`
+	JQL    string        `json:"jql,omitempty"`
+	From   time.Time     `json:"from"`
+	To     time.Time     `json:"to"`
+	// Unit is 'Work hours', or 'Days' with the full timeline
+	Unit        string         `json:"unit"`
+	WorkingTime exportWorkTime `json:"workingTime"`
+	// Window is the number of issues of the rolling average
+	Window int `json:"window"`
+	// Issues are the completed issues, by completion
+	Issues      []exportIssueTimes `json:"issues"`
+	Percentiles []exportPercentile `json:"percentiles"`
+	// Entries are the status transitions of the issues with the time in the previous status
+	Entries []exportEntry `json:"entries"`
+}
+type exportIssueTimes struct {
+	Key     string     `json:"key"`
+	Created *time.Time `json:"created"`
+	// Started is the first move to an in progress status, null if never in progress
+	Started   *time.Time `json:"started"`
+	Done      time.Time  `json:"done"`
+	LeadTime  *float64   `json:"leadTime"`
+	CycleTime *float64   `json:"cycleTime"`
+	// RollingAverage is the average cycle time of the last issues up to this one
+	RollingAverage *float64 `json:"rollingAverage"`
+}
+type exportPercentile struct {
+	Percentile int      `json:"percentile"`
+	LeadTime   *float64 `json:"leadTime"`
+	CycleTime  *float64 `json:"cycleTime"`
+}
+
+func (c *CycleTime) export() exporter {
+	start := *c.Sprint.StartDate
+	e := cycleTimeExport{
+		SchemaVersion: schemaVersion,
+		Generated:     time.Now(),
+		JQL:           c.opts.JQL,
+		From:          start,
+		To:            c.end,
+		Unit:          c.unit(),
+		WorkingTime:   exportWorkTimeOf(c.opts, c.Board, start),
+		Window:        c.window,
+		Issues:        []exportIssueTimes{},
+		Percentiles:   []exportPercentile{},
+		Entries:       []exportEntry{},
+	}
+	if c.opts.JQL == "" {
+		s := exportSprintOf(c.Sprint)
+		e.Sprint = &s
+	}
+	rows := c.rows()
+	for _, r := range rows {
+		e.Issues = append(e.Issues, exportIssueTimes{
+			Key:            r.Key,
+			Created:        exportTime(r.Created),
+			Started:        exportTime(r.Started),
+			Done:           r.Done,
+			LeadTime:       r.Lead,
+			CycleTime:      r.Cycle,
+			RollingAverage: r.Average,
+		})
+	}
+	for _, p := range cyclePercentiles(rows) {
+		e.Percentiles = append(e.Percentiles, exportPercentile{p.Percentile, p.Lead, p.Cycle})
+	}
+	for _, t := range c.issues {
+		prev := t.created
+		for _, tr := range t.transitions {
+			var value float64
+			if d := c.duration(prev, tr.time); d != nil {
+				value = *d
+			}
+			e.Entries = append(e.Entries, exportEntry{t.key, exportTime(tr.time), value, fmt.Sprintf("%s: status from %s to %s", t.key, tr.from, tr.to)})
+			prev = tr.time
+		}
+	}
+	return e
+}
+
+// writeCSV writes the issues with columns: key, created, started, done, lead_time, cycle_time, rolling_average
+func (e cycleTimeExport) writeCSV(w io.Writer) error {
+	value := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return formatFloat(*v)
+	}
+	c := csv.NewWriter(w)
+	c.Write([]string{"key", "created", "started", "done", "lead_time", "cycle_time", "rolling_average"})
+	for _, i := range e.Issues {
+		c.Write([]string{i.Key, csvTime(i.Created), csvTime(i.Started), csvTime(&i.Done), value(i.LeadTime), value(i.CycleTime), value(i.RollingAverage)})
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// writeEntriesCSV writes the status transitions with columns: list (issue key), time, value (time in the previous status), message
+func (e cycleTimeExport) writeEntriesCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"list", "time", "value", "message"})
+	for _, v := range e.Entries {
+		c.Write([]string{v.Bucket, csvTime(v.Time), formatFloat(v.Value), v.Message})
+	}
+	c.Flush()
+	return c.Error()
+}
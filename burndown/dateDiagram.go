@@ -3,7 +3,8 @@ package burndown
 import (
 	"html/template"
 	"io"
-	"log"
+	info "reports/jira"
+	"strconv"
 	"time"
 
 	jira "gopkg.in/andygrunwald/go-jira.v1"
@@ -13,19 +14,34 @@ type diagram struct {
 	Sprint    jira.Sprint
 	Entries   []tableEntry
 	StartLine bool
+	Unit      int
+	Label     string
+	Buckets   []string
+	Ideal     []idealPoint
 }
 type tableEntry struct {
-	Time     time.Time
-	New      int
-	Progress int
+	Time time.Time
+	// Remaining effort in each bucket
+	Remaining []int
+	Scope     int
 }
 
-func (d data) prepareDiagram(s jira.Sprint, startTime time.Time, startMargin bool) diagram {
+func (d data) prepareDiagram(s jira.Sprint, startTime time.Time, startMargin bool, workInfo info.BoardInfo, workdayStart, workdayEnd int) diagram {
 	sum := d.collapse(startTime)
-	return diagram{s, sum, startMargin}
+	conv := converter{
+		BoardInfo:         workInfo,
+		WorkDayStartHours: workdayStart,
+		WorkDayEndHours:   workdayEnd,
+	}
+	var ideal []idealPoint
+	if s.StartDate != nil {
+		conv.Start = *s.StartDate
+		ideal = conv.guideline(s, committed(sum, *s.StartDate))
+	}
+	return diagram{s, sum, startMargin, d.estimate.unit, d.estimate.label(), d.statuses.names, ideal}
 }
 
-func (d diagram) printDiagram(w io.Writer) {
+func (d diagram) printDiagram(w io.Writer) error {
 	t := `
 	   <div id="chart_div" style="width: 100%; height: 500px;"></div>
 	   <p>Sprint start: {{ .Sprint.StartDate }}</p>
@@ -39,57 +55,71 @@ func (d diagram) printDiagram(w io.Writer) {
 	var data = new google.visualization.DataTable();
 	data.addColumn('date', 'Time');
 	data.addColumn({type:'string', role:'annotation'});
-	data.addColumn('number', 'New');
-	data.addColumn('number', 'In Progress');
+	{{ range .Buckets }}data.addColumn('number', {{ . }});
+	{{ end }}data.addColumn('number', 'Scope change');
+	data.addColumn('number', 'Ideal');
 	data.addRows([
 		{{ range .Entries }}
-		[new Date(parseInt({{.Time.UnixNano }} /1000000)), null, {{ .New }}/3600,{{ .Progress}}/3600],{{ end }}
-		{{ if .StartLine }}[new Date(parseInt({{ .Sprint.StartDate.UnixNano }} /1000000)), "Sprint start",null,null],{{end}}
-		{{ if .Sprint.EndDate }}[new Date(parseInt({{ .Sprint.EndDate.UnixNano }} /1000000)), "Sprint end",null,null],{{end}}
-		[null,null,null,null]
+		[new Date(parseInt({{.Time.UnixNano }} /1000000)), null, {{ range .Remaining }}{{ . }}/{{ $.Unit }},{{ end }}{{ .Scope }}/{{ $.Unit }},null],{{ end }}
+		{{ range .Ideal }}
+		[new Date(parseInt({{.Time.UnixNano }} /1000000)), null, {{ range $.Buckets }}null,{{ end }}null,{{ .Value }}/{{ $.Unit }}],{{ end }}
+		{{ if .StartLine }}[new Date(parseInt({{ .Sprint.StartDate.UnixNano }} /1000000)), "Sprint start",{{ range .Buckets }}null,{{ end }}null,null],{{end}}
+		{{ if .Sprint.EndDate }}[new Date(parseInt({{ .Sprint.EndDate.UnixNano }} /1000000)), "Sprint end",{{ range .Buckets }}null,{{ end }}null,null],{{end}}
+		[null,null,{{ range .Buckets }}null,{{ end }}null,null]
 	]);
 
 		var options = {
 			title: 'Sprint Burndown (remaning effort) - {{ .Sprint.Name }}',
 			hAxis: {title: 'Days',  titleTextStyle: {color: '#333'}},
-			vAxis: {title: 'Hours remaining', minValue: 0},
+			vAxis: {title: {{ .Label }}, minValue: 0},
 			isStacked: true,
+			seriesType: 'area',
+			series: {{ lineSeries .Buckets }},
+			interpolateNulls: true,
 			annotations: {style:'line'}
 		};
 
-		var chart = new google.visualization.AreaChart(document.getElementById('chart_div'));
+		var chart = new google.visualization.ComboChart(document.getElementById('chart_div'));
 		chart.draw(data, options);
 	}
 	</script>`
-	tpl, err := template.New("t").Funcs(template.FuncMap{"now": time.Now}).Parse(t)
+	tpl, err := template.New("t").Funcs(template.FuncMap{"now": time.Now, "lineSeries": lineSeries}).Parse(t)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	err = tpl.Execute(w, d)
-	if err != nil {
-		log.Fatal(err)
+	return tpl.Execute(w, d)
+}
+
+// lineSeries gives the Google Charts series options drawing the scope and ideal series after the buckets as lines
+func lineSeries(buckets []string) map[string]map[string]interface{} {
+	return map[string]map[string]interface{}{
+		strconv.Itoa(len(buckets)):     {"type": "line"},
+		strconv.Itoa(len(buckets) + 1): {"type": "line", "lineDashStyle": []int{4, 4}, "color": "#888"},
 	}
 }
 
 func (d data) collapse(start time.Time) []tableEntry {
-	n := dedupe(d.new)
-	p := dedupe(d.inProgress)
+	var series [][]entry
+	for _, b := range d.buckets {
+		series = append(series, dedupe(b))
+	}
+	scope := len(series)
+	series = append(series, dedupe(d.scope))
 	var result []tableEntry
-	pi, ni := 0, 0
-	var v tableEntry
-	v.Time = time.Time{}
-	for pi < len(p) || ni < len(n) {
-		if pi < len(p) && v.Time == p[pi].Time {
-			v.Progress = p[pi].Value
-			pi++
+	idx := make([]int, len(series))
+	values := make([]int, len(series))
+	t := time.Time{}
+	for hasNext(idx, series) {
+		for s, e := range series {
+			if idx[s] < len(e) && t.Equal(e[idx[s]].Time) {
+				values[s] = e[idx[s]].Value
+				idx[s]++
+			}
 		}
-		if ni < len(n) && v.Time == n[ni].Time {
-			v.New = n[ni].Value
-			ni++
-		}
-		result = append(result, v)
-		v = tableEntry{Time: nextTime(pi, ni, p, n), New: v.New, Progress: v.Progress}
+		remaining := append([]int(nil), values[:scope]...)
+		result = append(result, tableEntry{Time: t, Remaining: remaining, Scope: values[scope]})
+		t = nextTime(idx, series)
 	}
 	if len(result) > 2 && result[0].Time.IsZero() {
 		//Eliminate the 0 time
@@ -98,17 +128,24 @@ func (d data) collapse(start time.Time) []tableEntry {
 	return result
 }
 
-func nextTime(pi, ni int, p, n []entry) time.Time {
-	var t time.Time
-	if pi < len(p) {
-		t = p[pi].Time
-		if ni < len(n) && n[ni].Time.Before(t) {
-			return n[ni].Time
+func hasNext(idx []int, series [][]entry) bool {
+	for s, e := range series {
+		if idx[s] < len(e) {
+			return true
 		}
-		return t
 	}
-	if ni < len(n) {
-		return n[ni].Time
+	return false
+}
+
+// nextTime finds the earliest time among the next entries of the series
+func nextTime(idx []int, series [][]entry) time.Time {
+	var t time.Time
+	found := false
+	for s, e := range series {
+		if idx[s] < len(e) && (!found || e[idx[s]].Time.Before(t)) {
+			t = e[idx[s]].Time
+			found = true
+		}
 	}
 	return t
 }
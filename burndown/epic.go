@@ -0,0 +1,391 @@
+package burndown
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	agile "reports/jira"
+	"strconv"
+	"strings"
+	"time"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// Epics is the progress of epics: the burndown of the child issues and their sub-tasks of every epic,
+// summarized in a portfolio table
+type Epics struct {
+	Board agile.BoardInfo
+	opts  Opts
+	end   time.Time
+	epics []*epicProgress
+	renderer
+}
+
+// epicProgress is the burndown of one epic with the state of its issues now
+type epicProgress struct {
+	key, summary string
+	data         *data
+	issues       []epicIssue
+}
+
+// epicIssue is the effort of an issue of an epic, done is the peak estimate of an issue in a done status
+type epicIssue struct {
+	key, status     string
+	remaining, done int
+	// estimated is whether the estimate was ever set, on the issue or in its changelog
+	estimated bool
+}
+
+// GenerateEpics collects the burndown of the epics with given keys, from the creation of their first issue until now.
+// The issues of an epic are the issues with the epic as Epic Link or as parent, and the sub-tasks of these.
+// The board of opts is optional, it gives the working days and the estimation field.
+func GenerateEpics(ctx context.Context, opts Opts, keys []string) (*Epics, error) {
+	err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, &OptionError{"epic", "", "at least one epic key"}
+	}
+	r := &Epics{Board: defaultWorkWeek(), opts: opts, end: time.Now()}
+	r.renderer = renderer{r}
+	if opts.Board != "" {
+		board, err := opts.Client.GetScrumBoardID(ctx, opts.Board, opts.BoardFilter, opts.Interactive)
+		if err != nil {
+			return nil, &StepError{"resolve board", err}
+		}
+		r.Board, err = opts.Client.GetBoardInfo(ctx, board)
+		if err != nil {
+			return nil, &StepError{"get board configuration", err}
+		}
+	}
+	//The status mapping is resolved once for all epics
+	opts.Statuses, err = opts.statusMapping(ctx, r.Board)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make(map[string]string)
+	err = opts.Client.SearchWithChangelog(ctx, fmt.Sprintf("key in (%s)", strings.Join(keys, ", ")), func(i jira.Issue) error {
+		summaries[i.Key] = i.Fields.Summary
+		return nil
+	})
+	if err != nil {
+		return nil, &StepError{"get epics", err}
+	}
+	for _, key := range keys {
+		if _, ok := summaries[key]; !ok {
+			return nil, &StepError{"get epics", &agile.NotFoundError{Kind: "epic", Name: key}}
+		}
+		e := &epicProgress{key: key, summary: summaries[key]}
+		e.data, err = newData(ctx, opts, time.Time{}, container{}, r.Board)
+		if err != nil {
+			return nil, err
+		}
+		e.data.start, e.data.fromCreation = time.Time{}, true
+		err = e.search(ctx, opts.Client)
+		if err != nil {
+			return nil, err
+		}
+		e.data.start = e.data.firstChange(r.end)
+		r.epics = append(r.epics, e)
+	}
+	return r, nil
+}
+
+// search collects the issues of the epic and then their sub-tasks
+func (e *epicProgress) search(ctx context.Context, j *agile.Client) error {
+	var children []string
+	err := j.SearchWithChangelog(ctx, fmt.Sprintf(`"Epic Link" = %s OR parent = %s`, e.key, e.key), func(i jira.Issue) error {
+		children = append(children, i.Key)
+		e.collect(i)
+		return nil
+	})
+	if err != nil {
+		return &StepError{"search issues of epic " + e.key, err}
+	}
+	if len(children) == 0 {
+		return nil
+	}
+	err = j.SearchWithChangelog(ctx, fmt.Sprintf("parent in (%s)", strings.Join(children, ", ")), func(i jira.Issue) error {
+		e.collect(i)
+		return nil
+	})
+	if err != nil {
+		return &StepError{"search sub-tasks of epic " + e.key, err}
+	}
+	return nil
+}
+
+// collect replays the issue into the burndown, its remaining effort now is what it added to the buckets.
+// The done effort is the peak estimate, as the remaining time is usually logged down before completion.
+func (e *epicProgress) collect(i jira.Issue) {
+	d := e.data
+	counts := make([]int, len(d.buckets))
+	for b := range d.buckets {
+		counts[b] = len(d.buckets[b])
+	}
+	d.collect(i, true)
+	is := epicIssue{key: i.Key, status: i.Fields.Status.Name}
+	for b := range d.buckets {
+		for _, v := range d.buckets[b][counts[b]:] {
+			is.remaining += v.Value
+		}
+	}
+	peak := peakEstimate(i, d.estimate)
+	is.estimated = peak > 0
+	if d.statuses.bucketOf(is.status) < 0 {
+		is.done = peak
+	}
+	e.issues = append(e.issues, is)
+}
+
+// epicSummary is a row of the portfolio table, effort is in the unit of the metric
+type epicSummary struct {
+	Key, Summary    string
+	Issues          int
+	Total, Done     float64
+	Remaining       float64
+	Complete        float64
+	WithoutEstimate int
+}
+
+// totals sums up the issues of the epic. Issues never estimated neither have remaining nor completed effort.
+func (e *epicProgress) totals() epicSummary {
+	s := epicSummary{Key: e.key, Summary: e.summary, Issues: len(e.issues)}
+	var remaining, done int
+	for _, i := range e.issues {
+		remaining += i.remaining
+		done += i.done
+		if !i.estimated {
+			s.WithoutEstimate++
+		}
+	}
+	s.Remaining, s.Done = e.data.estimate.display(remaining), e.data.estimate.display(done)
+	s.Total = s.Remaining + s.Done
+	s.Complete = percent(s.Done, s.Total)
+	return s
+}
+
+func percent(v, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return 100 * v / total
+}
+
+// portfolio gives the summary of every epic followed by the total of all epics with an empty key
+func (r *Epics) portfolio() []epicSummary {
+	var rows []epicSummary
+	total := epicSummary{Summary: "Total"}
+	for _, e := range r.epics {
+		s := e.totals()
+		rows = append(rows, s)
+		total.Issues += s.Issues
+		total.Total += s.Total
+		total.Done += s.Done
+		total.Remaining += s.Remaining
+		total.WithoutEstimate += s.WithoutEstimate
+	}
+	total.Complete = percent(total.Done, total.Total)
+	return append(rows, total)
+}
+
+func (r *Epics) converter(e *epicProgress) converter {
+	return workConverter(r.opts, r.Board, e.data.start)
+}
+
+func (r *Epics) prepareDiagram(e *epicProgress) cfdDiagram {
+	d, _ := e.data.stackedDiagram("Epic burndown - "+e.key+" "+e.summary, r.end, r.converter(e), r.opts.FullTimeline)
+	d.ID = "epic-" + e.key
+	return d
+}
+
+func (r *Epics) writeHTML(w io.Writer) error {
+	var err error
+	if r.opts.Renderer != SVG {
+		err = printHeader(w)
+		if err != nil {
+			return err
+		}
+	}
+	var unit string
+	if len(r.epics) > 0 {
+		unit = r.epics[0].data.estimate.name
+	}
+	err = executeTemplate(w, `
+	<h1>Epic progress</h1>
+	<table>
+	<tr><th>Epic</th><th>Summary</th><th>Issues</th><th>Total ({{ .Unit }})</th><th>Done</th><th>Remaining</th><th>% complete</th><th>Issues without estimate</th></tr>
+	{{ range .Rows }}
+	<tr><td>{{ .Key }}</td><td>{{ .Summary }}</td><td>{{ .Issues }}</td><td>{{ .Total }}</td><td>{{ .Done }}</td><td>{{ .Remaining }}</td><td>{{ printf "%.0f" .Complete }}</td><td>{{ .WithoutEstimate }}</td></tr>{{ end }}
+	</table>
+	Generated: {{ now }}`, struct {
+		Unit string
+		Rows []epicSummary
+	}{unit, r.portfolio()})
+	if err != nil {
+		return err
+	}
+	for _, e := range r.epics {
+		d := r.prepareDiagram(e)
+		if r.opts.Renderer == SVG {
+			err = d.printSVG(w)
+		} else {
+			err = d.printDiagram(w)
+		}
+		if err != nil {
+			return err
+		}
+		err = executeTemplate(w, `
+	<h2>Issues of {{ .Key }}</h2>
+	<p>From: {{ .From }}</p>
+	<table>
+	<tr><th>Issue</th><th>Status</th><th>Done</th><th>Remaining</th></tr>
+	{{ range .Issues }}
+	<tr><td>{{ .Key }}</td><td>{{ .Status }}</td><td>{{ .Done }}</td><td>{{ .Remaining }}</td></tr>{{ end }}
+	</table>`, struct {
+			Key    string
+			From   time.Time
+			Issues []exportEpicIssue
+		}{e.key, e.data.start, e.exportIssues()})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// epicsExport is the JSON document of the progress of epics, effort values are in the unit of the metric
+type epicsExport struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	Generated     time.Time      `json:"generated"`
+	To            time.Time      `json:"to"`
+	Metric        exportMetric   `json:"metric"`
+	WorkingTime   exportWorkTime `json:"workingTime"`
+	// Total is the portfolio total of all epics
+	Total exportEpicSummary `json:"total"`
+	Epics []exportEpic      `json:"epics"`
+}
+type exportEpicSummary struct {
+	IssueCount int     `json:"issueCount"`
+	Total      float64 `json:"total"`
+	Done       float64 `json:"done"`
+	Remaining  float64 `json:"remaining"`
+	// Complete is the done effort in percent of the total
+	Complete        float64 `json:"complete"`
+	WithoutEstimate int     `json:"withoutEstimate"`
+}
+type exportEpic struct {
+	Key     string `json:"key"`
+	Summary string `json:"summary"`
+	exportEpicSummary
+	// From is the creation of the first issue of the epic
+	From    time.Time         `json:"from"`
+	Buckets []string          `json:"buckets"`
+	Issues  []exportEpicIssue `json:"issues"`
+	// Series is the remaining effort in each bucket after each change
+	Series  []cfdExportPoint `json:"series"`
+	Entries []exportEntry    `json:"entries"`
+}
+type exportEpicIssue struct {
+	Key       string  `json:"key"`
+	Status    string  `json:"status"`
+	Done      float64 `json:"done"`
+	Remaining float64 `json:"remaining"`
+}
+
+func exportEpicSummaryOf(s epicSummary) exportEpicSummary {
+	return exportEpicSummary{s.Issues, s.Total, s.Done, s.Remaining, s.Complete, s.WithoutEstimate}
+}
+
+func (e *epicProgress) exportIssues() []exportEpicIssue {
+	issues := []exportEpicIssue{}
+	for _, i := range e.issues {
+		issues = append(issues, exportEpicIssue{i.key, i.status, e.data.estimate.display(i.done), e.data.estimate.display(i.remaining)})
+	}
+	return issues
+}
+
+func (r *Epics) export() exporter {
+	metric := r.opts.Metric
+	if metric == "" {
+		metric = RemainingTime
+	}
+	rows := r.portfolio()
+	start := r.end
+	for _, p := range r.epics {
+		if p.data.start.Before(start) {
+			start = p.data.start
+		}
+	}
+	e := epicsExport{
+		SchemaVersion: schemaVersion,
+		Generated:     time.Now(),
+		To:            r.end,
+		WorkingTime:   exportWorkTimeOf(r.opts, r.Board, start),
+		Total:         exportEpicSummaryOf(rows[len(rows)-1]),
+		Epics:         []exportEpic{},
+	}
+	for n, p := range r.epics {
+		d := p.data
+		e.Metric = exportMetric{metric, d.estimate.name}
+		ep := exportEpic{
+			Key:               p.key,
+			Summary:           p.summary,
+			exportEpicSummary: exportEpicSummaryOf(rows[n]),
+			From:              d.start,
+			Buckets:           d.statuses.names,
+			Issues:            p.exportIssues(),
+			Series:            []cfdExportPoint{},
+			Entries:           []exportEntry{},
+		}
+		for i, name := range d.statuses.names {
+			ep.Entries = append(ep.Entries, d.exportEntries(name, d.buckets[i])...)
+		}
+		conv := r.converter(p)
+		for _, s := range d.series(r.end) {
+			pt := cfdExportPoint{Time: s.Time}
+			for _, v := range s.Remaining {
+				pt.Values = append(pt.Values, d.estimate.display(v))
+			}
+			if !r.opts.FullTimeline {
+				h := conv.toSprintWorkTime(conv.Start, s.Time).Hours()
+				pt.WorkHours = &h
+			}
+			ep.Series = append(ep.Series, pt)
+		}
+		e.Epics = append(e.Epics, ep)
+	}
+	return e
+}
+
+// writeCSV writes the portfolio with columns: key, summary, issues, total, done, remaining, complete,
+// without_estimate. The total of all epics is last with the key empty.
+func (e epicsExport) writeCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"key", "summary", "issues", "total", "done", "remaining", "complete", "without_estimate"})
+	row := func(key, summary string, s exportEpicSummary) {
+		c.Write([]string{key, summary, strconv.Itoa(s.IssueCount), formatFloat(s.Total), formatFloat(s.Done), formatFloat(s.Remaining), formatFloat(s.Complete), strconv.Itoa(s.WithoutEstimate)})
+	}
+	for _, ep := range e.Epics {
+		row(ep.Key, ep.Summary, ep.exportEpicSummary)
+	}
+	row("", "Total", e.Total)
+	c.Flush()
+	return c.Error()
+}
+
+// writeEntriesCSV writes the entries with columns: list (epic key and bucket name, e.g. A-1/New), time, value, message
+func (e epicsExport) writeEntriesCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"list", "time", "value", "message"})
+	for _, ep := range e.Epics {
+		for _, v := range ep.Entries {
+			c.Write([]string{ep.Key + "/" + v.Bucket, csvTime(v.Time), formatFloat(v.Value), v.Message})
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
@@ -0,0 +1,42 @@
+package burndown
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OptionError is returned when the report options are invalid
+type OptionError struct {
+	Option string
+	Value  interface{}
+	// Expected describes the valid values
+	Expected string
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("invalid %s '%v', expected %s", e.Option, e.Value, e.Expected)
+}
+
+// StepError is returned when a step of generating or writing the report fails.
+// The cause, e.g. *jira.APIError or context.Canceled, is available with Unwrap.
+type StepError struct {
+	Step string
+	Err  error
+}
+
+func (e *StepError) Error() string {
+	return e.Step + ": " + e.Err.Error()
+}
+
+// Unwrap gives the cause of the failure
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+func oneOf(values ...interface{}) string {
+	s := make([]string, 0, len(values))
+	for _, v := range values {
+		s = append(s, fmt.Sprintf("'%v'", v))
+	}
+	return "one of " + strings.Join(s, ", ")
+}
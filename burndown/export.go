@@ -0,0 +1,332 @@
+package burndown
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	agile "reports/jira"
+	"strings"
+	"time"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// Format is an output format of the report
+type Format string
+
+const (
+	// HTML is the report page with the chart and the entry tables
+	HTML Format = "html"
+	// JSON is the full report data, see export for the schema
+	JSON Format = "json"
+	// CSV is the chart series
+	CSV Format = "csv"
+	// CSVEntries is the individual changes of the series as CSV
+	CSVEntries Format = "csv-entries"
+)
+
+// report is a generated report, rendered in every format by the renderer embedded in it
+type report interface {
+	writeHTML(w io.Writer) error
+	// export gives the JSON document of the report
+	export() exporter
+}
+
+// exporter is the JSON document of a report with the writers of its CSV files
+type exporter interface {
+	// writeCSV writes the main table of the report for CSV
+	writeCSV(w io.Writer) error
+	// writeEntriesCSV writes the changes behind the table for CSVEntries
+	writeEntriesCSV(w io.Writer) error
+}
+
+// renderer provides Render and WriteFiles to the report embedding it
+type renderer struct {
+	report
+}
+
+// Render writes the report in the given format: the HTML page, the JSON document or its CSV files
+func (r renderer) Render(w io.Writer, f Format) error {
+	switch f {
+	case HTML:
+		return r.writeHTML(w)
+	case JSON:
+		return writeJSON(w, r.export())
+	case CSV:
+		return r.export().writeCSV(w)
+	case CSVEntries:
+		return r.export().writeEntriesCSV(w)
+	}
+	return &OptionError{"format", f, oneOf(HTML, JSON, CSV, CSVEntries)}
+}
+
+// WriteFiles renders the report to files in the given formats, defaulting to HTML.
+// HTML is written to outfile, other formats next to it with the extension replaced.
+// CSV writes both the CSV and the '-entries.csv' file. Returns the files written.
+func (r renderer) WriteFiles(outfile string, formats ...Format) ([]string, error) {
+	if len(formats) == 0 {
+		formats = []Format{HTML}
+	}
+	var outputs []output
+	for _, f := range formats {
+		o, err := outputFiles(outfile, f)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, o...)
+	}
+	var files []string
+	for _, o := range outputs {
+		err := writeFile(o.file, func(w io.Writer) error {
+			return r.Render(w, o.format)
+		})
+		if err != nil {
+			return files, &StepError{"write " + o.file, err}
+		}
+		files = append(files, o.file)
+	}
+	return files, nil
+}
+
+// schemaVersion is increased on every incompatible change to the JSON and CSV exports
+const schemaVersion = 2
+
+// export is the JSON document of the report.
+// All effort values are in the unit of the metric (hours, story points or issues).
+type export struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	Generated     time.Time      `json:"generated"`
+	Sprint        exportSprint   `json:"sprint"`
+	Metric        exportMetric   `json:"metric"`
+	WorkingTime   exportWorkTime `json:"workingTime"`
+	// Buckets are the names of the stacked status buckets, in order from the bottom up
+	Buckets []string `json:"buckets"`
+	// Series is the remaining effort after each change, as drawn in the chart
+	Series []exportPoint `json:"series"`
+	// Entries are the individual changes of the buckets making up the series
+	Entries []exportEntry `json:"entries"`
+	// ScopeEntries are the additions to and removals from the sprint
+	ScopeEntries []exportEntry `json:"scopeEntries"`
+	// Guideline is the ideal burndown over the working time of the sprint, empty without sprint dates
+	Guideline []exportGuide `json:"guideline"`
+}
+type exportSprint struct {
+	ID            int        `json:"id"`
+	Name          string     `json:"name"`
+	State         string     `json:"state"`
+	StartDate     *time.Time `json:"startDate"`
+	EndDate       *time.Time `json:"endDate"`
+	CompleteDate  *time.Time `json:"completeDate"`
+	OriginBoardID int        `json:"originBoardId"`
+}
+type exportMetric struct {
+	// Name is one of 'time', 'points' or 'count'
+	Name Metric `json:"name"`
+	// Unit is the unit of all effort values
+	Unit string `json:"unit"`
+}
+type exportWorkTime struct {
+	// FullTimeline is false when the series are given in sprint work hours
+	FullTimeline bool `json:"fullTimeline"`
+	// ReportStart is the time the changes are collected from
+	ReportStart    time.Time `json:"reportStart"`
+	WorkdayStart   int       `json:"workdayStart"`
+	WorkdayEnd     int       `json:"workdayEnd"`
+	WeekDays       []string  `json:"weekDays"`
+	NonWorkingDays []string  `json:"nonWorkingDays"`
+}
+type exportPoint struct {
+	// Time is null for the state before the report start
+	Time *time.Time `json:"time"`
+	// WorkHours is the sprint work hours from sprint start, only in work hours mode
+	WorkHours *float64 `json:"workHours,omitempty"`
+	// Remaining effort of each bucket, in the order of buckets
+	Remaining []float64 `json:"remaining"`
+	Scope     float64   `json:"scope"`
+}
+type exportGuide struct {
+	Time time.Time `json:"time"`
+	// WorkHours is the sprint work hours from sprint start
+	WorkHours float64 `json:"workHours"`
+	Value     float64 `json:"value"`
+}
+type exportEntry struct {
+	// Bucket is the name of the bucket changed, omitted for scope entries
+	Bucket string `json:"bucket,omitempty"`
+	// Time is null for the state before the report start
+	Time    *time.Time `json:"time"`
+	Value   float64    `json:"value"`
+	Message string     `json:"message"`
+}
+
+func (d data) export(s jira.Sprint, opts Opts, bi agile.BoardInfo) export {
+	metric := opts.Metric
+	if metric == "" {
+		metric = RemainingTime
+	}
+	e := export{
+		SchemaVersion: schemaVersion,
+		Generated:     time.Now(),
+		Sprint:        exportSprintOf(s),
+		Metric:        exportMetric{metric, d.estimate.name},
+		WorkingTime:   exportWorkTimeOf(opts, bi, d.start),
+		Buckets:       d.statuses.names,
+		Series:        []exportPoint{},
+		Entries:       []exportEntry{},
+		ScopeEntries:  d.exportEntries("", d.scope),
+		Guideline:     []exportGuide{},
+	}
+	for i, name := range d.statuses.names {
+		e.Entries = append(e.Entries, d.exportEntries(name, d.buckets[i])...)
+	}
+
+	conv := converter{
+		BoardInfo:         bi,
+		WorkDayStartHours: opts.WorkdayStart,
+		WorkDayEndHours:   opts.WorkdayEnd,
+	}
+	workHours := !opts.FullTimeline && s.StartDate != nil
+	if s.StartDate != nil {
+		conv.Start = *s.StartDate
+	}
+	series := d.collapse(d.start)
+	for _, v := range series {
+		p := exportPoint{
+			Time:  exportTime(v.Time),
+			Scope: d.estimate.display(v.Scope),
+		}
+		for _, r := range v.Remaining {
+			p.Remaining = append(p.Remaining, d.estimate.display(r))
+		}
+		if workHours && p.Time != nil {
+			h := conv.toSprintWorkTime(conv.Start, v.Time).Hours()
+			p.WorkHours = &h
+		}
+		e.Series = append(e.Series, p)
+	}
+	if s.StartDate != nil {
+		for _, p := range conv.guideline(s, committed(series, *s.StartDate)) {
+			e.Guideline = append(e.Guideline, exportGuide{p.Time, p.Hours.Hours(), d.estimate.display(p.Value)})
+		}
+	}
+	return e
+}
+
+func exportSprintOf(s jira.Sprint) exportSprint {
+	return exportSprint{
+		ID:            s.ID,
+		Name:          s.Name,
+		State:         s.State,
+		StartDate:     s.StartDate,
+		EndDate:       s.EndDate,
+		CompleteDate:  s.CompleteDate,
+		OriginBoardID: s.OriginBoardID,
+	}
+}
+
+func exportWorkTimeOf(opts Opts, bi agile.BoardInfo, start time.Time) exportWorkTime {
+	w := exportWorkTime{
+		FullTimeline:   opts.FullTimeline,
+		ReportStart:    start,
+		WorkdayStart:   opts.WorkdayStart,
+		WorkdayEnd:     opts.WorkdayEnd,
+		WeekDays:       []string{},
+		NonWorkingDays: []string{},
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if bi.WeekDays[day] {
+			w.WeekDays = append(w.WeekDays, day.String())
+		}
+	}
+	for _, day := range bi.NonWorkingDays {
+		w.NonWorkingDays = append(w.NonWorkingDays, day.Format("2006-01-02"))
+	}
+	return w
+}
+
+func (d data) exportEntries(bucket string, entries []entry) []exportEntry {
+	result := make([]exportEntry, 0, len(entries))
+	sortByTime(entries)
+	for _, v := range entries {
+		result = append(result, exportEntry{bucket, exportTime(v.Time), d.estimate.display(v.Value), v.Msg})
+	}
+	return result
+}
+
+func exportTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// writeJSON writes the JSON document of a report
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeCSV writes the series with columns: time, work_hours, one column per bucket, scope
+func (e export) writeCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	c.Write(append(append([]string{"time", "work_hours"}, e.Buckets...), "scope"))
+	for _, p := range e.Series {
+		var workHours string
+		if p.WorkHours != nil {
+			workHours = formatFloat(*p.WorkHours)
+		}
+		row := []string{csvTime(p.Time), workHours}
+		for _, r := range p.Remaining {
+			row = append(row, formatFloat(r))
+		}
+		c.Write(append(row, formatFloat(p.Scope)))
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// writeEntriesCSV writes the entries with columns: list (bucket name or 'scope'), time, value, message
+func (e export) writeEntriesCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"list", "time", "value", "message"})
+	for _, v := range e.Entries {
+		c.Write([]string{v.Bucket, csvTime(v.Time), formatFloat(v.Value), v.Message})
+	}
+	for _, v := range e.ScopeEntries {
+		c.Write([]string{"scope", csvTime(v.Time), formatFloat(v.Value), v.Message})
+	}
+	c.Flush()
+	return c.Error()
+}
+
+func csvTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func formatFloat(v float64) string {
+	return fmt.Sprintf("%g", v)
+}
+
+// withSuffix replaces the extension of the file name with the suffix
+func withSuffix(file, suffix string) string {
+	return strings.TrimSuffix(file, filepath.Ext(file)) + suffix
+}
+
+func writeFile(name string, write func(io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
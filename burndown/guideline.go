@@ -0,0 +1,65 @@
+package burndown
+
+import (
+	"math"
+	"time"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// idealPoint is a point of the ideal burndown guideline
+type idealPoint struct {
+	Time time.Time
+	// Hours is the sprint work time from sprint start
+	Hours time.Duration
+	Value int
+}
+
+// committed gives the total remaining effort at the sprint start
+func committed(entries []tableEntry, start time.Time) int {
+	var total int
+	for _, e := range entries {
+		if e.Time.After(start) {
+			break
+		}
+		total = 0
+		for _, v := range e.Remaining {
+			total += v
+		}
+	}
+	return total
+}
+
+// guideline gives the ideal burndown from the committed effort at sprint start to zero at sprint end.
+// Effort burns only during working time, so there are points at the start and end of every working day.
+func (c converter) guideline(s jira.Sprint, committed int) []idealPoint {
+	if s.StartDate == nil || s.EndDate == nil {
+		return nil
+	}
+	start, end := *s.StartDate, *s.EndDate
+	total := c.toSprintWorkTime(start, end)
+	if total <= 0 {
+		return nil
+	}
+	point := func(t time.Time) idealPoint {
+		worked := c.toSprintWorkTime(start, t)
+		left := float64(committed) * (1 - float64(worked)/float64(total))
+		return idealPoint{t, worked, int(math.Round(left))}
+	}
+
+	points := []idealPoint{point(start)}
+	y, m, d := start.Date()
+	for day := time.Date(y, m, d, 0, 0, 0, 0, start.Location()); day.Before(end); day = day.AddDate(0, 0, 1) {
+		if !c.isWorkDay(day) {
+			continue
+		}
+		dy, dm, dd := day.Date()
+		for _, h := range []int{c.WorkDayStartHours, c.WorkDayEndHours} {
+			t := time.Date(dy, dm, dd, h, 0, 0, 0, day.Location())
+			if t.After(start) && t.Before(end) {
+				points = append(points, point(t))
+			}
+		}
+	}
+	return append(points, point(end))
+}
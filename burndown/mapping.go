@@ -0,0 +1,142 @@
+package burndown
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	agile "reports/jira"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Bucket is a named group of statuses, drawn as one stacked series in the chart
+type Bucket struct {
+	Name     string   `yaml:"name"`
+	Statuses []string `yaml:"statuses"`
+}
+
+// StatusMapping classifies statuses into buckets of remaining work.
+// Statuses not listed in any bucket or as done are counted in the first bucket.
+type StatusMapping struct {
+	// Buckets in the order they are stacked, from the bottom up
+	Buckets []Bucket `yaml:"buckets"`
+	// Done statuses are not counted as remaining work
+	Done []string `yaml:"done"`
+}
+
+// LoadStatusMapping reads the status mapping from a YAML file, e.g.
+//
+//	buckets:
+//	  - name: To Do
+//	    statuses: [Open, Reopened]
+//	  - name: Dev
+//	    statuses: [In Progress]
+//	  - name: Review
+//	    statuses: [In Review]
+//	done: [Done, Closed]
+func LoadStatusMapping(file string) (*StatusMapping, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var m StatusMapping
+	err = yaml.UnmarshalStrict(b, &m)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse status mapping %s: %v", file, err)
+	}
+	return &m, m.validate()
+}
+
+func (m StatusMapping) validate() error {
+	if len(m.Buckets) == 0 {
+		return &OptionError{"status mapping", "", "at least one bucket"}
+	}
+	seen := make(map[string]string)
+	for _, b := range m.Buckets {
+		if b.Name == "" {
+			return &OptionError{"status mapping", b.Statuses, "bucket with a name"}
+		}
+		for _, s := range b.Statuses {
+			if other, ok := seen[s]; ok {
+				return &OptionError{"status mapping", s, fmt.Sprintf("status in one bucket only, it is in '%s' and '%s'", other, b.Name)}
+			}
+			seen[s] = b.Name
+		}
+	}
+	for _, s := range m.Done {
+		if other, ok := seen[s]; ok {
+			return &OptionError{"status mapping", s, fmt.Sprintf("done status not to be in bucket '%s'", other)}
+		}
+	}
+	return nil
+}
+
+// classifier finds the bucket of a status
+type classifier struct {
+	names  []string
+	bucket map[string]int
+	done   map[string]bool
+}
+
+func (m StatusMapping) classifier() classifier {
+	c := classifier{bucket: make(map[string]int), done: make(map[string]bool)}
+	for i, b := range m.Buckets {
+		c.names = append(c.names, b.Name)
+		for _, s := range b.Statuses {
+			c.bucket[s] = i
+		}
+	}
+	for _, s := range m.Done {
+		c.done[s] = true
+	}
+	return c
+}
+
+// bucketOf gives the index of the bucket for the status, or -1 if the status is done
+func (c classifier) bucketOf(status string) int {
+	if c.done[status] {
+		return -1
+	}
+	return c.bucket[status]
+}
+
+// categoryMapping maps statuses by their status category: 'To Do' statuses and uncategorised ones are New,
+// 'In Progress' statuses In Progress and 'Done' statuses are done
+func categoryMapping(ctx context.Context, j *agile.Client) (StatusMapping, error) {
+	states, err := j.GetAllStatuses(ctx)
+	if err != nil {
+		return StatusMapping{}, err
+	}
+	m := StatusMapping{Buckets: []Bucket{{Name: "New"}, {Name: "In Progress"}}}
+	for _, s := range states {
+		switch s.StatusCategory.Key {
+		case jira.StatusCategoryInProgress:
+			m.Buckets[1].Statuses = append(m.Buckets[1].Statuses, s.Name)
+		case jira.StatusCategoryComplete:
+			m.Done = append(m.Done, s.Name)
+		default:
+			m.Buckets[0].Statuses = append(m.Buckets[0].Statuses, s.Name)
+		}
+	}
+	return m, nil
+}
+
+// columnMapping maps statuses by the board columns: every column is a bucket, except the last one which is done
+func columnMapping(bi agile.BoardInfo) (StatusMapping, error) {
+	var columns []agile.Column
+	for _, c := range bi.Columns {
+		if len(c.Statuses) > 0 {
+			columns = append(columns, c)
+		}
+	}
+	if len(columns) < 2 {
+		return StatusMapping{}, fmt.Errorf("board '%s' needs at least two columns with statuses, found %d", bi.Name, len(columns))
+	}
+	last := len(columns) - 1
+	m := StatusMapping{Done: columns[last].Statuses}
+	for _, c := range columns[:last] {
+		m.Buckets = append(m.Buckets, Bucket{c.Name, c.Statuses})
+	}
+	return m, m.validate()
+}
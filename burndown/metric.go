@@ -0,0 +1,106 @@
+package burndown
+
+import (
+	"fmt"
+	"log"
+	"math"
+	agile "reports/jira"
+	"strconv"
+	"strings"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// Metric selects the estimation statistic tracked by the burndown
+type Metric string
+
+const (
+	// RemainingTime tracks the remaining time estimate of the issues
+	RemainingTime Metric = "time"
+	// StoryPoints tracks the estimation field configured for the board
+	StoryPoints Metric = "points"
+	// IssueCount tracks the number of issues
+	IssueCount Metric = "count"
+)
+
+// pointsUnit is the scale story points are collected in, so fractional points survive integer sums
+const pointsUnit = 100
+
+// estimator describes how the tracked statistic is read from the issue and its changelog
+type estimator struct {
+	// field is the changelog field name of the statistic, empty if the value never changes
+	field string
+	// unit is the collected value that equals one displayed unit
+	unit int
+	// name of the displayed unit
+	name string
+	// parse converts the changelog value to the collected value
+	parse func(string) int
+	// current gives the collected value of the issue as it is now
+	current func(jira.Issue) int
+}
+
+func newEstimator(m Metric, bi agile.BoardInfo) (estimator, error) {
+	switch m {
+	case RemainingTime, "":
+		return estimator{
+			field: "timeestimate",
+			unit:  3600,
+			name:  "Hours",
+			parse: parseInt,
+			current: func(i jira.Issue) int {
+				return i.Fields.TimeEstimate
+			},
+		}, nil
+	case StoryPoints:
+		f := bi.Estimation
+		if !strings.HasPrefix(f.FieldID, "customfield_") {
+			return estimator{}, fmt.Errorf("board '%s' does not use a custom field for estimation (uses '%s')", bi.Name, f.Name)
+		}
+		return estimator{
+			field: f.Name,
+			unit:  pointsUnit,
+			name:  f.Name,
+			parse: parsePoints,
+			current: func(i jira.Issue) int {
+				v, ok := i.Fields.Unknowns[f.FieldID].(float64)
+				if !ok {
+					return 0
+				}
+				return int(math.Round(v * pointsUnit))
+			},
+		}, nil
+	case IssueCount:
+		return estimator{
+			unit:  1,
+			name:  "Issues",
+			parse: parseInt,
+			current: func(jira.Issue) int {
+				return 1
+			},
+		}, nil
+	}
+	return estimator{}, fmt.Errorf("unknown metric '%s', expected one of: %s, %s, %s", m, RemainingTime, StoryPoints, IssueCount)
+}
+
+// label is the axis title of the remaining value
+func (e estimator) label() string {
+	return e.name + " remaining"
+}
+
+// display converts collected value to displayed units
+func (e estimator) display(v int) float64 {
+	return float64(v) / float64(e.unit)
+}
+
+func parsePoints(s string) int {
+	input := strings.TrimSpace(s)
+	if input == "" || input == "null" {
+		return 0
+	}
+	value, err := strconv.ParseFloat(input, 64)
+	if err != nil {
+		log.Print("Problem parsing to number", s, err)
+	}
+	return int(math.Round(value * pointsUnit))
+}
@@ -0,0 +1,238 @@
+package burndown
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	agile "reports/jira"
+	"time"
+)
+
+// Release is the burndown of a fix version: the remaining effort of every issue ever in the version
+// over the lifetime of the version
+type Release struct {
+	Version agile.Version
+	// Project is the key or ID of the project of the version
+	Project string
+	Board   agile.BoardInfo
+	opts    Opts
+	// end is the release for released versions, now otherwise
+	end  time.Time
+	data *data
+	renderer
+}
+
+// GenerateRelease collects the burndown of the version of the project, given by name or ID,
+// following the issues added to and removed from the version by their changelog.
+// The report starts at the start date of the version, or else at the creation of its first issue,
+// in the working days of the board of opts if given.
+func GenerateRelease(ctx context.Context, opts Opts, project, version string) (*Release, error) {
+	err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+	v, err := opts.Client.FindVersion(ctx, project, version, opts.Interactive)
+	if err != nil {
+		return nil, &StepError{"resolve version", err}
+	}
+	id, ok := agile.GetNumber(v.ID)
+	if !ok {
+		return nil, &StepError{"resolve version", fmt.Errorf("version '%s' has no numeric ID: %s", v.Name, v.ID)}
+	}
+	r := &Release{Version: v, Project: project, Board: defaultWorkWeek(), opts: opts}
+	r.renderer = renderer{r}
+	if opts.Board != "" {
+		board, err := opts.Client.GetScrumBoardID(ctx, opts.Board, opts.BoardFilter, opts.Interactive)
+		if err != nil {
+			return nil, &StepError{"resolve board", err}
+		}
+		r.Board, err = opts.Client.GetBoardInfo(ctx, board)
+		if err != nil {
+			return nil, &StepError{"get board configuration", err}
+		}
+	}
+	r.data, err = newData(ctx, opts, v.StartDate, container{"Fix Version", id}, r.Board)
+	if err != nil {
+		return nil, err
+	}
+	if v.StartDate.IsZero() {
+		r.data.start = time.Time{}
+	}
+	r.data.fromCreation = true
+	err = opts.Client.SearchWithChangelog(ctx, fmt.Sprintf("fixVersion = %v", id), r.data.collector(true))
+	if err != nil {
+		return nil, &StepError{"search version issues", err}
+	}
+	//Issues removed from the version don't match it anymore, look for them by its history
+	jql := fmt.Sprintf("fixVersion WAS %v AND (fixVersion is EMPTY OR fixVersion != %v)", id, id)
+	if !v.StartDate.IsZero() {
+		jql += fmt.Sprintf(` AND updated >= "%s"`, r.data.start.Format("2006-01-02 15:04"))
+	}
+	err = opts.Client.SearchWithChangelog(ctx, jql, r.data.collector(false))
+	if err != nil {
+		return nil, &StepError{"search removed issues", err}
+	}
+	r.end = time.Now()
+	if release := r.releaseTime(); v.Released && !release.IsZero() && release.Before(r.end) {
+		r.end = release
+	}
+	if r.data.start.IsZero() {
+		r.data.start = r.data.firstChange(r.end)
+	}
+	r.data.truncate(r.end)
+	return r, nil
+}
+
+// releaseTime is the end of the working day of the release date, zero without release date
+func (r *Release) releaseTime() time.Time {
+	d := r.Version.ReleaseDate
+	if d.IsZero() {
+		return d
+	}
+	return time.Date(d.Year(), d.Month(), d.Day(), r.opts.WorkdayEnd, 0, 0, 0, d.Location())
+}
+
+// firstChange gives the time of the earliest change, or end without changes
+func (d *data) firstChange(end time.Time) time.Time {
+	first := end
+	for _, entries := range append(append([][]entry(nil), d.buckets...), d.scope) {
+		for _, e := range entries {
+			if !e.Time.IsZero() && e.Time.Before(first) {
+				first = e.Time
+			}
+		}
+	}
+	return first
+}
+
+func (r *Release) converter() converter {
+	return workConverter(r.opts, r.Board, r.data.start)
+}
+
+func (r *Release) prepareDiagram() cfdDiagram {
+	d, x := r.data.stackedDiagram("Release burndown - "+r.Version.Name, r.end, r.converter(), r.opts.FullTimeline)
+	d.Markers = append(d.Markers, svgMarker{x(r.data.start), "Version start"})
+	if release := r.releaseTime(); !release.IsZero() {
+		d.Markers = append(d.Markers, svgMarker{x(release), "Release date"})
+	}
+	return d
+}
+
+func (r *Release) writeHTML(w io.Writer) error {
+	d := r.prepareDiagram()
+	var err error
+	if r.opts.Renderer == SVG {
+		err = d.printSVG(w)
+	} else {
+		err = printHeader(w)
+		if err != nil {
+			return err
+		}
+		err = d.printDiagram(w)
+	}
+	if err != nil {
+		return err
+	}
+	err = executeTemplate(w, `
+	<p>Version start: {{ .Start }}</p>
+	<p>Release date: {{ if .Release.IsZero }}not set{{ else }}{{ .Release }}{{ end }}{{ if .Released }} (released){{ end }}</p>
+	<p>To: {{ .To }}</p>
+	Generated: {{ now }}`, struct {
+		Start, Release, To time.Time
+		Released           bool
+	}{r.data.start, r.releaseTime(), r.end, r.Version.Released})
+	if err != nil {
+		return err
+	}
+	for i, name := range r.data.statuses.names {
+		err = printTable(w, name, r.data.buckets[i], r.data.estimate)
+		if err != nil {
+			return err
+		}
+	}
+	return printTable(w, "Scope changes", r.data.scope, r.data.estimate)
+}
+
+// releaseExport is the JSON document of the release burndown, effort values are in the unit of the metric
+type releaseExport struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	Generated     time.Time      `json:"generated"`
+	Version       exportVersion  `json:"version"`
+	Project       string         `json:"project"`
+	From          time.Time      `json:"from"`
+	To            time.Time      `json:"to"`
+	Metric        exportMetric   `json:"metric"`
+	WorkingTime   exportWorkTime `json:"workingTime"`
+	// Buckets are the names of the status buckets in workflow order
+	Buckets []string `json:"buckets"`
+	// Series is the remaining effort in each bucket after each change
+	Series []cfdExportPoint `json:"series"`
+	// Entries are the individual changes of the buckets making up the series
+	Entries []exportEntry `json:"entries"`
+	// ScopeEntries are the issues added to or removed from the version, with their remaining effort
+	ScopeEntries []exportEntry `json:"scopeEntries"`
+}
+type exportVersion struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Released bool   `json:"released"`
+	// StartDate and ReleaseDate are null if not set
+	StartDate   *time.Time `json:"startDate"`
+	ReleaseDate *time.Time `json:"releaseDate"`
+}
+
+func (r *Release) export() exporter {
+	metric := r.opts.Metric
+	if metric == "" {
+		metric = RemainingTime
+	}
+	d := r.data
+	v := r.Version
+	e := releaseExport{
+		SchemaVersion: schemaVersion,
+		Generated:     time.Now(),
+		Version:       exportVersion{v.ID, v.Name, v.Released, exportTime(v.StartDate), exportTime(v.ReleaseDate)},
+		Project:       r.Project,
+		From:          d.start,
+		To:            r.end,
+		Metric:        exportMetric{metric, d.estimate.name},
+		WorkingTime:   exportWorkTimeOf(r.opts, r.Board, d.start),
+		Buckets:       d.statuses.names,
+		Series:        []cfdExportPoint{},
+		Entries:       []exportEntry{},
+		ScopeEntries:  d.exportEntries("scope", d.scope),
+	}
+	for i, name := range d.statuses.names {
+		e.Entries = append(e.Entries, d.exportEntries(name, d.buckets[i])...)
+	}
+	conv := r.converter()
+	for _, s := range d.series(r.end) {
+		p := cfdExportPoint{Time: s.Time}
+		for _, v := range s.Remaining {
+			p.Values = append(p.Values, d.estimate.display(v))
+		}
+		if !r.opts.FullTimeline {
+			h := conv.toSprintWorkTime(conv.Start, s.Time).Hours()
+			p.WorkHours = &h
+		}
+		e.Series = append(e.Series, p)
+	}
+	return e
+}
+
+// writeCSV writes the series like the cumulative flow
+func (e releaseExport) writeCSV(w io.Writer) error {
+	return cfdExport{Buckets: e.Buckets, Series: e.Series}.writeCSV(w)
+}
+
+// writeEntriesCSV writes the entries with columns: list (bucket name or scope), time, value, message
+func (e releaseExport) writeEntriesCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"list", "time", "value", "message"})
+	for _, v := range append(append([]exportEntry(nil), e.Entries...), e.ScopeEntries...) {
+		c.Write([]string{v.Bucket, csvTime(v.Time), formatFloat(v.Value), v.Message})
+	}
+	c.Flush()
+	return c.Error()
+}
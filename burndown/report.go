@@ -1,11 +1,11 @@
 package burndown
 
 import (
+	"context"
 	"fmt"
 	"html/template"
 	"io"
 	"log"
-	"os"
 	agile "reports/jira"
 	"sort"
 	"strconv"
@@ -16,10 +16,21 @@ import (
 )
 
 type data struct {
-	start                              time.Time
-	progressCategory, completeCategory map[string]bool
-	inProgress                         []entry
-	new                                []entry
+	start    time.Time
+	in       container
+	statuses classifier
+	estimate estimator
+	// buckets hold the remaining effort changes of each status bucket
+	buckets [][]entry
+	// scope holds the effort added to or removed from the sprint after start
+	scope []entry
+	// done holds the effort completed by issues moving to a done status, negative when reopened
+	done []entry
+	// fromCreation counts the issues created after start from their creation, for reports without a sprint
+	fromCreation bool
+	// byAssignee buckets the remaining effort by assignee instead of status, the status names are replaced
+	// by the assignees in order of appearance
+	byAssignee bool
 }
 type entry struct {
 	Time  time.Time
@@ -27,65 +38,271 @@ type entry struct {
 	Msg   string
 }
 
+// Opts selects the sprint and configures the burndown report
 type Opts struct {
 	*agile.Client
 	Board, Sprint             string
 	Interactive               bool
-	Outfile                   string
 	StartMargin, FullTimeline bool
 	// defines the working time in a day
 	WorkdayStart, WorkdayEnd int
+	// Metric is the estimation statistic to burn down, defaults to RemainingTime
+	Metric Metric
+	// Renderer draws the charts, defaults to GoogleCharts
+	Renderer Renderer
+	// Statuses maps the statuses to the stacked buckets,
+	// defaults to New and In Progress buckets by the status categories
+	Statuses *StatusMapping
+	// BoardColumns stacks the board columns instead, the last column being done
+	BoardColumns bool
+	// ByAssignee stacks the remaining effort by assignee instead, with an Unassigned bucket.
+	// The status mapping only tells the done statuses.
+	ByAssignee bool
+	// BoardFilter narrows the boards when the board is given by name or chosen interactively
+	BoardFilter agile.BoardFilter
+	// JQL selects the issues instead of a sprint, for reports over the time range From - To.
+	// A time range without JQL reports the issues of the board, e.g. of a kanban board.
+	JQL      string
+	From, To time.Time
 }
 
-// Run creates the burndown report for remaining effort for given sprint
+// Report is the collected burndown of a sprint, ready to be rendered
+type Report struct {
+	Sprint jira.Sprint
+	Board  agile.BoardInfo
+	opts   Opts
+	data   *data
+	renderer
+}
+
+func (opts Opts) validate() error {
+	if opts.WorkdayStart < 0 || 23 < opts.WorkdayStart {
+		return &OptionError{"workday start", opts.WorkdayStart, "hour in range 0-23"}
+	}
+	if opts.WorkdayEnd < 0 || 23 < opts.WorkdayEnd {
+		return &OptionError{"workday end", opts.WorkdayEnd, "hour in range 0-23"}
+	}
+	if opts.WorkdayEnd <= opts.WorkdayStart {
+		return &OptionError{"workday end", opts.WorkdayEnd, fmt.Sprintf("hour after workday start %v", opts.WorkdayStart)}
+	}
+	switch opts.Metric {
+	case "", RemainingTime, StoryPoints, IssueCount:
+	default:
+		return &OptionError{"metric", opts.Metric, oneOf(RemainingTime, StoryPoints, IssueCount)}
+	}
+	switch opts.Renderer {
+	case "", GoogleCharts, SVG:
+	default:
+		return &OptionError{"renderer", opts.Renderer, oneOf(GoogleCharts, SVG)}
+	}
+	if opts.timeRange() && (opts.From.IsZero() || !opts.To.After(opts.From)) {
+		return &OptionError{"time range", fmt.Sprintf("%v - %v", opts.From, opts.To), "start before end"}
+	}
+	if opts.Statuses != nil {
+		if opts.BoardColumns {
+			return &OptionError{"board columns", opts.BoardColumns, "no status mapping to be given"}
+		}
+		return opts.Statuses.validate()
+	}
+	return nil
+}
+
+// Generate collects the burndown report for remaining effort for given sprint
 // sprint can be provided as JIRA internal sprint ID or as sprint name
-func Run(opts Opts) {
-	s, err := getSprint(opts.Client, opts.Board, opts.Sprint, opts.Interactive)
+func Generate(ctx context.Context, opts Opts) (*Report, error) {
+	err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+	if opts.timeRange() {
+		return generateTimeRange(ctx, opts)
+	}
+	s, err := getSprint(ctx, opts)
 	if err != nil {
-		log.Fatalln(err)
+		return nil, &StepError{"resolve sprint", err}
 	}
 	var start time.Time
 	if s.StartDate != nil {
 		start = *s.StartDate
 	}
-	data := &data{start: start}
+	bi, err := opts.Client.GetBoardInfo(ctx, s.OriginBoardID)
+	if err != nil {
+		return nil, &StepError{"get board configuration", err}
+	}
+	data, err := newData(ctx, opts, start, sprintContainer(s.ID), bi)
+	if err != nil {
+		return nil, err
+	}
+	err = searchSprintIssues(ctx, opts.Client, s, data.start, data.collector)
+	if err != nil {
+		return nil, err
+	}
+	return newReport(s, bi, opts, data), nil
+}
+
+func newReport(s jira.Sprint, bi agile.BoardInfo, opts Opts, d *data) *Report {
+	r := &Report{Sprint: s, Board: bi, opts: opts, data: d}
+	r.renderer = renderer{r}
+	return r
+}
+
+// generateTimeRange collects the burndown of the issues of the JQL or the board of opts from From to To,
+// counting the issues created later from their creation
+func generateTimeRange(ctx context.Context, opts Opts) (*Report, error) {
+	sc, err := opts.scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts.JQL = sc.jql
+	data, err := newData(ctx, opts, opts.From, container{}, sc.board)
+	if err != nil {
+		return nil, err
+	}
+	data.fromCreation = true
+	err = opts.searchScope(ctx, sc, data.collector)
+	if err != nil {
+		return nil, err
+	}
+	data.truncate(sc.end)
+	return newReport(sc.sprint, sc.board, opts, data), nil
+}
+
+// newData prepares the collection of the burndown from start with the status mapping and metric of opts
+func newData(ctx context.Context, opts Opts, start time.Time, in container, bi agile.BoardInfo) (*data, error) {
+	data := &data{start: start, in: in}
 	if opts.StartMargin {
 		data.start = data.start.Add(-24 * time.Hour)
 	}
-	data.progressCategory, data.completeCategory, err = getStates(opts.Client)
+	mapping, err := opts.statusMapping(ctx, bi)
 	if err != nil {
-		log.Fatalln(err)
+		return nil, err
 	}
-	sOpts := &jira.SearchOptions{Expand: "changelog"}
-	err = opts.Client.Issue.SearchPages(fmt.Sprintf("Sprint = %v ", s.ID), sOpts, data.collect)
+	data.statuses = mapping.classifier()
+	data.buckets = make([][]entry, len(data.statuses.names))
+	if opts.ByAssignee {
+		data.byAssignee, data.statuses.names, data.buckets = true, nil, nil
+	}
+	data.estimate, err = newEstimator(opts.Metric, bi)
 	if err != nil {
-		log.Fatalln(err)
+		return nil, &StepError{"select metric", err}
 	}
+	return data, nil
+}
 
-	f, err := os.Create(opts.Outfile)
+// truncate drops the changes after end
+func (d *data) truncate(end time.Time) {
+	before := func(entries []entry) []entry {
+		var result []entry
+		for _, e := range entries {
+			if !e.Time.After(end) {
+				result = append(result, e)
+			}
+		}
+		return result
+	}
+	for i := range d.buckets {
+		d.buckets[i] = before(d.buckets[i])
+	}
+	d.scope, d.done = before(d.scope), before(d.done)
+}
+
+// statusMapping gives the status mapping of the options, or the default mapping for the board
+func (opts Opts) statusMapping(ctx context.Context, bi agile.BoardInfo) (*StatusMapping, error) {
+	if opts.Statuses != nil {
+		return opts.Statuses, nil
+	}
+	var m StatusMapping
+	var err error
+	if opts.BoardColumns {
+		m, err = columnMapping(bi)
+	} else {
+		m, err = categoryMapping(ctx, opts.Client)
+	}
 	if err != nil {
-		log.Fatalln(err)
+		return nil, &StepError{"get statuses", err}
 	}
-	defer f.Close()
+	return &m, nil
+}
 
-	err = printHeader(f)
+// searchSprintIssues finds the issues of the sprint and the issues removed from it since start.
+// collector gives the callback for the issues, member tells if they are currently in the sprint.
+func searchSprintIssues(ctx context.Context, j *agile.Client, s jira.Sprint, start time.Time, collector func(member bool) func(jira.Issue) error) error {
+	err := j.SearchWithChangelog(ctx, fmt.Sprintf("Sprint = %v ", s.ID), collector(true))
+	if err != nil {
+		return &StepError{"search sprint issues", err}
+	}
+	if s.StartDate == nil {
+		return nil
+	}
+	//Issues removed from the sprint don't match the sprint anymore, look for them from the board issues updated since
+	b, err := j.GetBoard(ctx, s.OriginBoardID)
+	if err != nil {
+		return &StepError{"get board", err}
+	}
+	jql := fmt.Sprintf(`filter = %v AND updated >= "%s" AND (Sprint is EMPTY OR Sprint != %v)`, b.FilterID, start.Format("2006-01-02 15:04"), s.ID)
+	err = j.SearchWithChangelog(ctx, jql, collector(false))
 	if err != nil {
-		log.Fatalln(err)
+		return &StepError{"search removed issues", err}
+	}
+	return nil
+}
+
+func (r *Report) writeHTML(w io.Writer) error {
+	return r.data.writeHTML(w, r.Sprint, r.opts, r.Board)
+}
+
+func (r *Report) export() exporter {
+	return r.data.export(r.Sprint, r.opts, r.Board)
+}
+
+type output struct {
+	format Format
+	file   string
+}
+
+// outputFiles gives the files to render for the requested format
+func outputFiles(outfile string, f Format) ([]output, error) {
+	switch f {
+	case HTML:
+		return []output{{HTML, outfile}}, nil
+	case JSON:
+		return []output{{JSON, withSuffix(outfile, ".json")}}, nil
+	case CSV:
+		return []output{{CSV, withSuffix(outfile, ".csv")}, {CSVEntries, withSuffix(outfile, "-entries.csv")}}, nil
+	}
+	return nil, &OptionError{"format", f, oneOf(HTML, JSON, CSV)}
+}
+
+func (d *data) writeHTML(f io.Writer, s jira.Sprint, opts Opts, bi agile.BoardInfo) error {
+	var diag interface {
+		printDiagram(io.Writer) error
+		printSVG(io.Writer) error
 	}
 	if opts.FullTimeline {
-		diag := data.prepareDiagram(s, data.start, opts.StartMargin)
-		diag.printDiagram(f)
+		diag = d.prepareDiagram(s, d.start, opts.StartMargin, bi, opts.WorkdayStart, opts.WorkdayEnd)
+	} else {
+		diag = d.prepareWorkHoursDiagram(s, d.start, opts.StartMargin, bi, opts.WorkdayStart, opts.WorkdayEnd)
+	}
+	var err error
+	if opts.Renderer == SVG {
+		err = diag.printSVG(f)
 	} else {
-		bi, err := opts.Client.GetBoardInfo(s.OriginBoardID)
+		err = printHeader(f)
+		if err != nil {
+			return err
+		}
+		err = diag.printDiagram(f)
+	}
+	if err != nil {
+		return err
+	}
+	for i, name := range d.statuses.names {
+		err = printTable(f, name, d.buckets[i], d.estimate)
 		if err != nil {
-			log.Fatalln(err)
+			return err
 		}
-		hd := data.prepareWorkHoursDiagram(s, data.start, opts.StartMargin, bi, opts.WorkdayStart, opts.WorkdayEnd)
-		hd.printDiagram(f)
 	}
-	printTable(f, "New", data.new)
-	printTable(f, "Progress", data.inProgress)
-	log.Println("Report written to: " + opts.Outfile)
+	return printTable(f, "Scope", d.scope, d.estimate)
 }
 
 func printHeader(w io.Writer) error {
@@ -93,80 +310,143 @@ func printHeader(w io.Writer) error {
 	return err
 }
 
-func getStates(j *agile.Client) (map[string]bool, map[string]bool, error) {
-	cat, _, err := j.StatusCategory.GetList()
-	if err != nil {
-		return nil, nil, err
+// defaultWorkWeek is the working time of reports without a board: Monday to Friday without holidays
+func defaultWorkWeek() agile.BoardInfo {
+	bi := agile.BoardInfo{WeekDays: make(map[time.Weekday]bool)}
+	for day := time.Monday; day <= time.Friday; day++ {
+		bi.WeekDays[day] = true
 	}
-	var progID, doneID int
-	for _, c := range cat {
-		if c.ColorName == "yellow" {
-			progID = c.ID
+	return bi
+}
+
+// reportScope is what a report covers: a started sprint, or the issues of a JQL query over a time range
+type reportScope struct {
+	// sprint is the reported sprint, or a sprint without ID spanning the time range
+	sprint jira.Sprint
+	// board gives the working time
+	board agile.BoardInfo
+	// end is the sprint completion, now for sprints still running, or the end of the time range
+	end time.Time
+	// jql selects the issues of the time range: the JQL of opts or the filter of the board, empty for sprints
+	jql string
+}
+
+// timeRange tells if the report is over the time range From - To instead of a sprint
+func (opts Opts) timeRange() bool {
+	return opts.JQL != "" || !opts.From.IsZero()
+}
+
+// scope resolves the sprint of opts, or the time range with the JQL of opts or else the issues of the board,
+// so kanban boards can be reported. The board is optional with JQL, defaulting to defaultWorkWeek.
+func (opts Opts) scope(ctx context.Context) (reportScope, error) {
+	if opts.timeRange() {
+		from, to := opts.From, opts.To
+		sc := reportScope{sprint: jira.Sprint{Name: opts.JQL, StartDate: &from, EndDate: &to}, end: to, jql: opts.JQL}
+		if opts.JQL != "" && opts.Board == "" {
+			sc.board = defaultWorkWeek()
+			return sc, nil
+		}
+		board, err := opts.Client.GetScrumBoardID(ctx, opts.Board, opts.BoardFilter, opts.Interactive)
+		if err != nil {
+			return sc, &StepError{"resolve board", err}
+		}
+		sc.board, err = opts.Client.GetBoardInfo(ctx, board)
+		if err != nil {
+			return sc, &StepError{"get board configuration", err}
 		}
-		if c.ColorName == "green" {
-			doneID = c.ID
+		if sc.jql == "" {
+			b, err := opts.Client.GetBoard(ctx, board)
+			if err != nil {
+				return sc, &StepError{"get board", err}
+			}
+			//Issues resolved before the time range don't change during it, like the removed issues of searchSprintIssues
+			sc.sprint.Name = b.Name
+			sc.jql = fmt.Sprintf(`filter = %v AND (updated >= "%s" OR resolution is EMPTY)`, b.FilterID, from.Format("2006-01-02 15:04"))
 		}
+		return sc, nil
 	}
-	//Categorise all statuses
-	states, err := j.GetAllStatuses()
+	s, err := getSprint(ctx, opts)
 	if err != nil {
-		return nil, nil, err
+		return reportScope{}, &StepError{"resolve sprint", err}
 	}
-	progress, done := make(map[string]bool), make(map[string]bool)
-	for _, s := range states {
-		if s.StatusCategory.ID == progID {
-			progress[s.Name] = true
-		}
-		if s.StatusCategory.ID == doneID {
-			done[s.Name] = true
-		}
+	if s.StartDate == nil {
+		return reportScope{}, &StepError{"resolve sprint", fmt.Errorf("sprint '%s' has not started", s.Name)}
 	}
-	return progress, done, nil
+	sc := reportScope{sprint: s, end: sprintEnd(s)}
+	if now := time.Now(); sc.end.IsZero() || sc.end.After(now) {
+		sc.end = now
+	}
+	sc.board, err = opts.Client.GetBoardInfo(ctx, s.OriginBoardID)
+	if err != nil {
+		return sc, &StepError{"get board configuration", err}
+	}
+	return sc, nil
 }
 
-func getSprint(j *agile.Client, board, sprint string, interactive bool) (jira.Sprint, error) {
-	sprintID, isID := agile.GetNumber(sprint)
+// workConverter gives the converter to work time of the board and the working day of opts from start
+func workConverter(opts Opts, bi agile.BoardInfo, start time.Time) converter {
+	return converter{
+		BoardInfo:         bi,
+		Start:             start,
+		WorkDayStartHours: opts.WorkdayStart,
+		WorkDayEndHours:   opts.WorkdayEnd,
+	}
+}
+
+// searchScope searches the issues of the JQL of the scope, or of its sprint
+func (opts Opts) searchScope(ctx context.Context, sc reportScope, collector func(member bool) func(jira.Issue) error) error {
+	if sc.jql == "" {
+		return searchSprintIssues(ctx, opts.Client, sc.sprint, *sc.sprint.StartDate, collector)
+	}
+	err := opts.Client.SearchWithChangelog(ctx, sc.jql, collector(true))
+	if err != nil {
+		return &StepError{"search issues", err}
+	}
+	return nil
+}
+
+func getSprint(ctx context.Context, opts Opts) (jira.Sprint, error) {
+	sprintID, isID := agile.GetNumber(opts.Sprint)
 	if !isID {
-		b, err := j.GetScrumBoardID(board, interactive)
+		b, err := opts.Client.GetScrumBoardID(ctx, opts.Board, opts.BoardFilter, opts.Interactive)
 		if err != nil {
 			return jira.Sprint{}, err
 		}
-		if sprint == "" && !interactive {
-			return j.GetActiveSprint(b)
+		if opts.Sprint == "" && !opts.Interactive {
+			return opts.Client.GetActiveSprint(ctx, b)
 		}
-		return j.FindSprint(b, sprint, interactive)
+		return opts.Client.FindSprint(ctx, b, opts.Sprint, opts.Interactive)
 	}
-	return j.GetSprint(sprintID)
+	return opts.Client.GetSprint(ctx, sprintID)
 }
 
 func print(d *data) {
-	fmt.Println("New..")
-	for _, v := range d.new {
-		fmt.Printf("%v, %v\n", v.Time, v.Value)
-	}
-	fmt.Println("Progress..")
-	for _, v := range d.inProgress {
-		fmt.Printf("%v, %v\n", v.Time, v.Value)
+	for i, name := range d.statuses.names {
+		fmt.Println(name + "..")
+		for _, v := range d.buckets[i] {
+			fmt.Printf("%v, %v\n", v.Time, v.Value)
+		}
 	}
 }
-func printTable(w io.Writer, header string, d []entry) {
+func printTable(w io.Writer, header string, d []entry, e estimator) error {
 	t := `
 	<h2>{{ .Name }}</h2>
 	<table>
-	<tr><th>Time</th><th>Effort (secs)</th><th>Description</th></tr>
+	<tr><th>Time</th><th>{{ .Unit }}</th><th>Description</th></tr>
 	{{ range .Entries }}
 	<tr>
-		<td>{{if .Time.IsZero }}before start of sprint{{else}}{{.Time }}{{end}}</td><td>{{ .Value }}</td><td>{{ .Msg }}</td>
+		<td>{{if .Time.IsZero }}before start of sprint{{else}}{{.Time }}{{end}}</td><td>{{ display .Value }}</td><td>{{ .Msg }}</td>
 	</tr>{{ end }}
 	</table>`
-	tpl, err := template.New("t").Parse(t)
+	tpl, err := template.New("t").Funcs(template.FuncMap{"display": e.display}).Parse(t)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
-	tpl.Execute(w, struct {
+	return tpl.Execute(w, struct {
 		Name    string
+		Unit    string
 		Entries []entry
-	}{header, d})
+	}{header, e.name, d})
 }
 
 func dedupe(d []entry) []entry {
@@ -194,55 +474,145 @@ func sortByTime(v []entry) {
 	})
 }
 
-func (d *data) collect(i jira.Issue) error {
-	changes := getChangesAfter(i, d.start)
-	lastStatus, lastEstimate := changes[0].newStatus, changes[0].newTime
+// collector gives the search callback for issues, member tells if the found issues are currently in the sprint
+func (d *data) collector(member bool) func(jira.Issue) error {
+	return func(i jira.Issue) error {
+		return d.collect(i, member)
+	}
+}
+
+// members gives the search callback for reports of the issues currently in the sprint, skipping the removed ones
+func members(collect func(jira.Issue)) func(member bool) func(jira.Issue) error {
+	return func(member bool) func(jira.Issue) error {
+		return func(i jira.Issue) error {
+			if member {
+				collect(i)
+			}
+			return nil
+		}
+	}
+}
+
+func (d *data) collect(i jira.Issue, member bool) error {
+	changes := getChangesAfter(i, d.start, d.estimate, d.in, member)
+	created := time.Time(i.Fields.Created)
+	switch {
+	case !created.After(d.start):
+	case d.fromCreation:
+		changes[0].time = created
+	case changes[0].inSprint:
+		//Created in the sprint after its start without a sprint change, it is added to the scope on creation
+		changes[0].inSprint = false
+		added := change{time: created, sprintChange: true, inSprint: true}
+		changes = append(changes[:1], append([]change{added}, changes[1:]...)...)
+	}
+	if !member && !everInSprint(changes) {
+		//Issue was not part of the sprint during the report
+		return nil
+	}
+	lastStatus, lastEstimate, inSprint := changes[0].newStatus, changes[0].newTime, changes[0].inSprint
+	lastAssignee := changes[0].newAssignee
 	for _, change := range changes {
+		if !inSprint {
+			//Only follow the issue state until it is added to the sprint
+			if change.statusChange {
+				lastStatus = change.newStatus
+			}
+			if change.timeChange {
+				lastEstimate = change.newTime
+			}
+			if change.assigneeChange {
+				lastAssignee = change.newAssignee
+			}
+			if change.sprintChange && change.inSprint {
+				d.addScopeChange(lastStatus, lastAssignee, change.time, lastEstimate, fmt.Sprintf("%s: scope added (status %s)", i.Key, lastStatus))
+				inSprint = true
+			}
+			continue
+		}
+		//The assignee after the change, the old status stays with the previous one
+		assignee := lastAssignee
+		if change.assigneeChange {
+			assignee = change.newAssignee
+		}
 		//Find status at time index
 		if change.statusChange {
 			if change.timeChange {
 				//status and estimate change
 				if !change.time.IsZero() {
-					d.addTimeEstimateChange(change.oldStatus, change.time, -change.oldTime, fmt.Sprintf("%s: change of status (from %s) and estimate", i.Key, change.oldStatus))
+					d.addTimeEstimateChange(change.oldStatus, lastAssignee, change.time, -change.oldTime, fmt.Sprintf("%s: change of status (from %s) and estimate", i.Key, change.oldStatus))
 				}
-				d.addTimeEstimateChange(change.newStatus, change.time, change.newTime, fmt.Sprintf("%s: updated status (to %s) and changed estimate", i.Key, change.newStatus))
+				d.addTimeEstimateChange(change.newStatus, assignee, change.time, change.newTime, fmt.Sprintf("%s: updated status (to %s) and changed estimate", i.Key, change.newStatus))
+				d.addCompletion(change, peakEstimate(i, d.estimate), i.Key)
 				lastEstimate = change.newTime
 			} else {
 				//only status change
 				if !change.time.IsZero() {
-					d.addTimeEstimateChange(change.oldStatus, change.time, -lastEstimate, fmt.Sprintf("%s: change of status from %s", i.Key, change.oldStatus))
+					d.addTimeEstimateChange(change.oldStatus, lastAssignee, change.time, -lastEstimate, fmt.Sprintf("%s: change of status from %s", i.Key, change.oldStatus))
 				}
-				d.addTimeEstimateChange(change.newStatus, change.time, lastEstimate, fmt.Sprintf("%s: updated status to %s", i.Key, change.newStatus))
+				d.addTimeEstimateChange(change.newStatus, assignee, change.time, lastEstimate, fmt.Sprintf("%s: updated status to %s", i.Key, change.newStatus))
+				d.addCompletion(change, peakEstimate(i, d.estimate), i.Key)
 			}
 			lastStatus = change.newStatus
-		} else {
+		} else if change.assigneeChange && d.byAssignee {
+			//assignee change, the remaining effort moves to the new assignee
+			before, after := lastEstimate, lastEstimate
+			if change.timeChange {
+				before, after = change.oldTime, change.newTime
+				lastEstimate = change.newTime
+			}
+			d.addTimeEstimateChange(lastStatus, lastAssignee, change.time, -before, fmt.Sprintf("%s: reassigned to %s", i.Key, assigneeName(assignee)))
+			d.addTimeEstimateChange(lastStatus, assignee, change.time, after, fmt.Sprintf("%s: reassigned from %s", i.Key, assigneeName(lastAssignee)))
+		} else if change.timeChange {
 			//only estimate change
 			estimate := change.newTime
 			if !change.time.IsZero() {
 				estimate -= change.oldTime
 			}
-			d.addTimeEstimateChange(lastStatus, change.time, estimate, fmt.Sprintf("%s: changed estimate", i.Key))
+			d.addTimeEstimateChange(lastStatus, assignee, change.time, estimate, fmt.Sprintf("%s: changed estimate", i.Key))
 			lastEstimate = change.newTime
 		}
+		lastAssignee = assignee
+		if change.sprintChange && !change.inSprint {
+			d.addScopeChange(lastStatus, lastAssignee, change.time, -lastEstimate, fmt.Sprintf("%s: scope removed (status %s)", i.Key, lastStatus))
+			inSprint = false
+		}
 	}
 	return nil
 }
 
-//Get the changes after the give timestamp, at minimum gives the initial state with zero-time
-func getChangesAfter(i jira.Issue, t time.Time) []change {
-	changes := getStatusAndEstimateChanges(i.Changelog.Histories, t)
+func everInSprint(changes []change) bool {
+	for _, c := range changes {
+		if c.inSprint {
+			return true
+		}
+	}
+	return false
+}
+
+// Get the changes after the give timestamp, at minimum gives the initial state with zero-time
+func getChangesAfter(i jira.Issue, t time.Time, e estimator, in container, member bool) []change {
+	changes := getStatusAndEstimateChanges(i.Changelog.Histories, t, e, in)
 
 	//Find initial values
 	var initialStatus string
 	initialEstimate := -1
+	initialSprint, sprintFound := member, false
+	initialAssignee, assigneeFound := currentAssignee(i), false
 	for _, c := range changes {
 		if c.statusChange && initialStatus == "" {
 			initialStatus = c.oldStatus
 		}
+		if c.assigneeChange && !assigneeFound {
+			initialAssignee, assigneeFound = c.oldAssignee, true
+		}
 		if c.timeChange && initialEstimate == -1 {
 			initialEstimate = c.oldTime
 		}
-		if initialStatus != "" && initialEstimate != -1 {
+		if c.sprintChange && !sprintFound {
+			initialSprint, sprintFound = c.wasInSprint, true
+		}
+		if initialStatus != "" && initialEstimate != -1 && sprintFound && assigneeFound {
 			break
 		}
 	}
@@ -250,10 +620,18 @@ func getChangesAfter(i jira.Issue, t time.Time) []change {
 		initialStatus = i.Fields.Status.Name
 	}
 	if initialEstimate == -1 {
-		initialEstimate = i.Fields.TimeEstimate
+		initialEstimate = e.current(i)
 	}
 
-	return append([]change{change{time.Time{}, true, "", initialStatus, true, 0, initialEstimate}}, changes...)
+	initial := change{
+		statusChange: true,
+		newStatus:    initialStatus,
+		timeChange:   true,
+		newTime:      initialEstimate,
+		inSprint:     initialSprint,
+		newAssignee:  initialAssignee,
+	}
+	return append([]change{initial}, changes...)
 }
 
 type change struct {
@@ -264,9 +642,16 @@ type change struct {
 	timeChange   bool
 	oldTime      int
 	newTime      int
+	sprintChange bool
+	wasInSprint  bool
+	inSprint     bool
+	// the assignees are display names, empty if unassigned
+	assigneeChange bool
+	oldAssignee    string
+	newAssignee    string
 }
 
-func getStatusAndEstimateChanges(histories []jira.ChangelogHistory, start time.Time) (result []change) {
+func getStatusAndEstimateChanges(histories []jira.ChangelogHistory, start time.Time, e estimator, in container) (result []change) {
 	prevEstimate := -1
 	for _, h := range histories {
 		time, err := h.CreatedTime()
@@ -278,60 +663,147 @@ func getStatusAndEstimateChanges(histories []jira.ChangelogHistory, start time.T
 			continue
 		}
 
-		statusChange, oldState, newState, timeChange, oldEstimate, newEstimate := changedStateOrEstimate(h, prevEstimate)
-		if statusChange || timeChange {
-			result = append(result, change{time, statusChange, oldState, newState, timeChange, oldEstimate, newEstimate})
+		c := changedStateOrEstimate(h, prevEstimate, e, in)
+		if c.statusChange || c.timeChange || c.sprintChange || c.assigneeChange {
+			c.time = time
+			result = append(result, c)
 		}
-		if timeChange {
-			prevEstimate = newEstimate
+		if c.timeChange {
+			prevEstimate = c.newTime
 		}
 	}
 	return
 }
-func changedStateOrEstimate(h jira.ChangelogHistory, prev int) (stateChanged bool, oldState string, newState string, timeChanged bool, oldValue int, newValue int) {
+func changedStateOrEstimate(h jira.ChangelogHistory, prev int, e estimator, in container) (c change) {
 	for _, it := range h.Items {
-		if it.Field == "timeestimate" {
-			oldValue = parseInt(it.FromString)
-			if prev != -1 && oldValue != prev {
-				log.Printf("change history detail %s: weird state old estimates don't match, got %v, but expected previous %v. Using previous value instead.", h.Id, oldValue, prev)
+		if e.field != "" && it.Field == e.field {
+			c.oldTime = e.parse(it.FromString)
+			if prev != -1 && c.oldTime != prev {
+				log.Printf("change history detail %s: weird state old estimates don't match, got %v, but expected previous %v. Using previous value instead.", h.Id, c.oldTime, prev)
 				//Correct for inconsistencies
-				oldValue = prev
+				c.oldTime = prev
 			}
-			newValue = parseInt(it.ToString)
-			timeChanged = timeChanged || oldValue != newValue
+			c.newTime = e.parse(it.ToString)
+			c.timeChange = c.timeChange || c.oldTime != c.newTime
 		}
 		if it.Field == "status" {
-			oldState = it.FromString
-			newState = it.ToString
-			stateChanged = true
+			c.oldStatus = it.FromString
+			c.newStatus = it.ToString
+			c.statusChange = true
+		}
+		if it.Field == "assignee" {
+			c.oldAssignee = it.FromString
+			c.newAssignee = it.ToString
+			c.assigneeChange = true
+		}
+		if it.Field == in.field && (containsSprint(it.From, in.id) || containsSprint(it.To, in.id)) {
+			//Fix versions are logged as one item per version added or removed
+			c.wasInSprint = containsSprint(it.From, in.id)
+			c.inSprint = containsSprint(it.To, in.id)
+			c.sprintChange = c.wasInSprint != c.inSprint
 		}
 	}
 	return
 }
 
-// state, change time, change
-func (d *data) addTimeEstimateChange(t string, time time.Time, diff int, msg string) {
-	if diff == 0 || d.isDone(t) {
+// container is the sprint or fix version the issues are followed in and out of by their changelog,
+// the zero container follows none
+type container struct {
+	// field is the changelog field of the membership
+	field string
+	id    int
+}
+
+func sprintContainer(id int) container {
+	return container{"Sprint", id}
+}
+
+// containsSprint checks if the sprint or version is in the changelog value of comma separated IDs
+func containsSprint(ids interface{}, sprint int) bool {
+	s, _ := ids.(string)
+	for _, id := range strings.Split(s, ",") {
+		if v, ok := agile.GetNumber(strings.TrimSpace(id)); ok && v == sprint {
+			return true
+		}
+	}
+	return false
+}
+
+// addScopeChange records issue being added to or removed from the sprint with its remaining effort
+func (d *data) addScopeChange(t, assignee string, time time.Time, diff int, msg string) {
+	if diff == 0 || d.statuses.bucketOf(t) < 0 {
 		return
 	}
-	var update *[]entry
-	if d.isInProgress(t) {
-		update = &d.inProgress
-	} else {
-		update = &d.new
+	d.addTimeEstimateChange(t, assignee, time, diff, msg)
+	d.scope = append(d.scope, entry{time, diff, msg})
+}
+
+// addCompletion records the effort of an issue moving to a done status, or moving back from it
+func (d *data) addCompletion(c change, effort int, key string) {
+	wasDone, isDone := d.statuses.bucketOf(c.oldStatus) < 0, d.statuses.bucketOf(c.newStatus) < 0
+	switch {
+	case c.time.IsZero() || wasDone == isDone || effort == 0:
+	case isDone:
+		d.done = append(d.done, entry{c.time, effort, fmt.Sprintf("%s: completed (status %s)", key, c.newStatus)})
+	case wasDone:
+		d.done = append(d.done, entry{c.time, -effort, fmt.Sprintf("%s: reopened (status %s)", key, c.newStatus)})
+	}
+}
+
+// peakEstimate gives the highest estimate in the history of the issue, its effort when done,
+// as the remaining time is usually logged down before completion
+func peakEstimate(i jira.Issue, e estimator) int {
+	peak := 0
+	for _, c := range getChangesAfter(i, time.Time{}, e, container{}, true) {
+		if c.timeChange && c.newTime > peak {
+			peak = c.newTime
+		}
+	}
+	return peak
+}
+
+// state, assignee, change time, change
+func (d *data) addTimeEstimateChange(t, assignee string, time time.Time, diff int, msg string) {
+	if diff == 0 || d.statuses.bucketOf(t) < 0 {
+		return
+	}
+	b := d.bucketOf(t, assignee)
+	d.buckets[b] = append(d.buckets[b], entry{time, diff, msg})
+}
+
+// Unassigned is the bucket of the issues without assignee when stacking by assignee
+const Unassigned = "Unassigned"
+
+// bucketOf gives the bucket of the remaining effort in a status that is not done,
+// the bucket of the assignee is added on first use when stacking by assignee
+func (d *data) bucketOf(status, assignee string) int {
+	if !d.byAssignee {
+		return d.statuses.bucketOf(status)
+	}
+	name := assigneeName(assignee)
+	for b, n := range d.statuses.names {
+		if n == name {
+			return b
+		}
 	}
-	updated := append(*update, entry{time, diff, msg})
-	*update = updated
+	d.statuses.names = append(d.statuses.names, name)
+	d.buckets = append(d.buckets, nil)
+	return len(d.buckets) - 1
 }
 
-// is progress
-func (d *data) isInProgress(state string) bool {
-	return d.progressCategory[state]
+func assigneeName(assignee string) string {
+	if assignee == "" {
+		return Unassigned
+	}
+	return assignee
 }
 
-// is done
-func (d *data) isDone(state string) bool {
-	return d.completeCategory[state]
+// currentAssignee is the display name of the assignee of the issue, empty if unassigned
+func currentAssignee(i jira.Issue) string {
+	if i.Fields.Assignee == nil {
+		return ""
+	}
+	return i.Fields.Assignee.DisplayName
 }
 
 func parseInt(s string) int {
@@ -0,0 +1,454 @@
+package burndown
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// Renderer selects how the charts of the report are drawn
+type Renderer string
+
+const (
+	// GoogleCharts draws the charts in the browser using the Google Charts library loaded from the internet
+	GoogleCharts Renderer = "google"
+	// SVG draws the charts as inline SVG, making the report a single file without external requests
+	SVG Renderer = "svg"
+)
+
+// palette is the default series colours of Google Charts, so both renderers look alike
+var palette = []string{"#3366cc", "#dc3912", "#ff9900", "#109618", "#990099", "#0099c6", "#dd4477", "#66aa00", "#b82e2e", "#316395"}
+
+const (
+	svgWidth, svgHeight                              = 1000, 500
+	marginLeft, marginRight, marginTop, marginBottom = 70, 160, 40, 60
+)
+
+// svgChart is a chart with stacked area series and line series over a shared X axis
+type svgChart struct {
+	Title, XTitle, YTitle string
+	X                     []float64
+	// Stacked series are drawn as stacked areas, in order from bottom up
+	Stacked []svgSeries
+	// Lines are drawn on top of the areas without stacking
+	Lines []svgSeries
+	// Points are drawn as dots without lines, after the lines
+	Points  []svgSeries
+	Markers []svgMarker
+	// XTicks gives the tick positions and labels for the X axis range
+	XTicks func(min, max float64) ([]float64, func(float64) string)
+}
+type svgSeries struct {
+	Name   string
+	Values []float64
+	// X overrides the chart X values for a line with its own points
+	X []float64
+	// Dashed lines are drawn grey and dashed, as guides
+	Dashed bool
+	// Labels are the tooltips of the points
+	Labels []string
+}
+
+// svgMarker is an annotation line across the chart at given X
+type svgMarker struct {
+	X     float64
+	Label string
+}
+
+func (c svgChart) render(w io.Writer) error {
+	xMin, xMax := c.xRange()
+	yMin, yMax := c.yRange()
+	yTicks, yMin, yMax := niceTicks(yMin, yMax, 8)
+	plotW, plotH := float64(svgWidth-marginLeft-marginRight), float64(svgHeight-marginTop-marginBottom)
+	px := func(x float64) float64 {
+		return marginLeft + (x-xMin)/(xMax-xMin)*plotW
+	}
+	py := func(y float64) float64 {
+		return marginTop + plotH - (y-yMin)/(yMax-yMin)*plotH
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="100%%" font-family="Arial, sans-serif" font-size="12">`+"\n", svgWidth, svgHeight)
+	fmt.Fprintf(&b, `<text x="%d" y="20" font-size="14" font-weight="bold">%s</text>`+"\n", marginLeft, esc(c.Title))
+
+	//Grid and Y axis
+	for _, t := range yTicks {
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ccc"/>`+"\n", marginLeft, py(t), marginLeft+plotW, py(t))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle">%s</text>`+"\n", marginLeft-5, py(t), formatNumber(t))
+	}
+	fmt.Fprintf(&b, `<text transform="translate(15,%.1f) rotate(-90)" text-anchor="middle">%s</text>`+"\n", marginTop+plotH/2, esc(c.YTitle))
+
+	//X axis
+	xTicks, label := c.XTicks(xMin, xMax)
+	for _, t := range xTicks {
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#333"/>`+"\n", px(t), marginTop+plotH, px(t), marginTop+plotH+5)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle">%s</text>`+"\n", px(t), marginTop+plotH+18, esc(label(t)))
+	}
+	fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#333"/>`+"\n", marginLeft, py(math.Max(yMin, 0)), marginLeft+plotW, py(math.Max(yMin, 0)))
+	fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle" fill="#333">%s</text>`+"\n", marginLeft+plotW/2, svgHeight-15, esc(c.XTitle))
+
+	//Stacked areas
+	base := make([]float64, len(c.X))
+	for s, series := range c.Stacked {
+		top := make([]float64, len(c.X))
+		var points []string
+		for i, x := range c.X {
+			top[i] = base[i] + series.Values[i]
+			points = append(points, fmt.Sprintf("%.1f,%.1f", px(x), py(top[i])))
+		}
+		for i := len(c.X) - 1; i >= 0; i-- {
+			points = append(points, fmt.Sprintf("%.1f,%.1f", px(c.X[i]), py(base[i])))
+		}
+		colour := palette[s%len(palette)]
+		fmt.Fprintf(&b, `<polygon points="%s" fill="%s" fill-opacity="0.3" stroke="none"/>`+"\n", strings.Join(points, " "), colour)
+		fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`+"\n", strings.Join(points[:len(c.X)], " "), colour)
+		base = top
+	}
+
+	//Lines
+	for l, series := range c.Lines {
+		var points []string
+		for i, x := range series.xValues(c.X) {
+			points = append(points, fmt.Sprintf("%.1f,%.1f", px(x), py(series.Values[i])))
+		}
+		dash := ""
+		if series.Dashed {
+			dash = ` stroke-dasharray="6,4"`
+		}
+		fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"%s/>`+"\n", strings.Join(points, " "), c.colour(len(c.Stacked)+l), dash)
+	}
+
+	//Points
+	for p, series := range c.Points {
+		colour := c.colour(len(c.Stacked) + len(c.Lines) + p)
+		for i, x := range series.xValues(c.X) {
+			var title string
+			if i < len(series.Labels) {
+				title = "<title>" + esc(series.Labels[i]) + "</title>"
+			}
+			fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="4" fill="%s">%s</circle>`+"\n", px(x), py(series.Values[i]), colour, title)
+		}
+	}
+
+	//Annotations
+	for _, m := range c.Markers {
+		fmt.Fprintf(&b, `<line x1="%.1f" y1="%d" x2="%.1f" y2="%.1f" stroke="#999" stroke-dasharray="4,3"/>`+"\n", px(m.X), marginTop, px(m.X), marginTop+plotH)
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" text-anchor="middle" fill="#555">%s</text>`+"\n", px(m.X), marginTop-5, esc(m.Label))
+	}
+
+	//Legend
+	for i, name := range c.seriesNames() {
+		y := marginTop + 10 + i*20
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%d" width="12" height="12" fill="%s"/>`+"\n", marginLeft+plotW+15, y, c.colour(i))
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d">%s</text>`+"\n", marginLeft+plotW+32, y+10, esc(name))
+	}
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// svgBarChart is a chart of bars grouped by category, one bar of each series per category
+type svgBarChart struct {
+	Title, YTitle string
+	Categories    []string
+	Series        []svgSeries
+}
+
+func (c svgBarChart) render(w io.Writer) error {
+	values := []float64{0}
+	for _, s := range c.Series {
+		values = append(values, s.Values...)
+	}
+	yTicks, yMin, yMax := niceTicks(0, math.Max(bounds(values)), 8)
+	plotW, plotH := float64(svgWidth-marginLeft-marginRight), float64(svgHeight-marginTop-marginBottom)
+	py := func(y float64) float64 {
+		return marginTop + plotH - (y-yMin)/(yMax-yMin)*plotH
+	}
+	group := plotW / math.Max(float64(len(c.Categories)), 1)
+	bar := group * 0.8 / math.Max(float64(len(c.Series)), 1)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="100%%" font-family="Arial, sans-serif" font-size="12">`+"\n", svgWidth, svgHeight)
+	fmt.Fprintf(&b, `<text x="%d" y="20" font-size="14" font-weight="bold">%s</text>`+"\n", marginLeft, esc(c.Title))
+	for _, t := range yTicks {
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ccc"/>`+"\n", marginLeft, py(t), marginLeft+plotW, py(t))
+		fmt.Fprintf(&b, `<text x="%d" y="%.1f" text-anchor="end" dominant-baseline="middle">%s</text>`+"\n", marginLeft-5, py(t), formatNumber(t))
+	}
+	fmt.Fprintf(&b, `<text transform="translate(15,%.1f) rotate(-90)" text-anchor="middle">%s</text>`+"\n", marginTop+plotH/2, esc(c.YTitle))
+	fmt.Fprintf(&b, `<line x1="%d" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#333"/>`+"\n", marginLeft, py(0), marginLeft+plotW, py(0))
+
+	for i, category := range c.Categories {
+		x := marginLeft + float64(i)*group
+		for s, series := range c.Series {
+			v := series.Values[i]
+			fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s: %s</title></rect>`+"\n",
+				x+group*0.1+float64(s)*bar, py(v), bar, py(0)-py(v), palette[s%len(palette)], esc(series.Name), formatNumber(v))
+		}
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" text-anchor="middle">%s</text>`+"\n", x+group/2, marginTop+plotH+18, esc(category))
+	}
+
+	for i, s := range c.Series {
+		y := marginTop + 10 + i*20
+		fmt.Fprintf(&b, `<rect x="%.1f" y="%d" width="12" height="12" fill="%s"/>`+"\n", marginLeft+plotW+15, y, palette[i%len(palette)])
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d">%s</text>`+"\n", marginLeft+plotW+32, y+10, esc(s.Name))
+	}
+	b.WriteString("</svg>\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (c svgChart) seriesNames() []string {
+	var names []string
+	for _, s := range c.Stacked {
+		names = append(names, s.Name)
+	}
+	for _, s := range c.Lines {
+		names = append(names, s.Name)
+	}
+	for _, s := range c.Points {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// colour gives the colour of the i-th series, counting the stacked series first, then the lines
+func (c svgChart) colour(i int) string {
+	if l := i - len(c.Stacked); l >= 0 && l < len(c.Lines) && c.Lines[l].Dashed {
+		return "#888"
+	}
+	return palette[i%len(palette)]
+}
+
+func (s svgSeries) xValues(x []float64) []float64 {
+	if s.X != nil {
+		return s.X
+	}
+	return x
+}
+
+func (c svgChart) xRange() (float64, float64) {
+	values := append([]float64(nil), c.X...)
+	for _, s := range append(append([]svgSeries(nil), c.Lines...), c.Points...) {
+		if s.X != nil {
+			values = append(values, s.X...)
+		}
+	}
+	for _, m := range c.Markers {
+		values = append(values, m.X)
+	}
+	min, max := bounds(values)
+	if min == max {
+		max = min + 1
+	}
+	return min, max
+}
+
+func (c svgChart) yRange() (float64, float64) {
+	values := []float64{0}
+	for i := range c.X {
+		sum := 0.0
+		for _, s := range c.Stacked {
+			sum += s.Values[i]
+			values = append(values, sum)
+		}
+		for _, s := range append(append([]svgSeries(nil), c.Lines...), c.Points...) {
+			if s.X == nil {
+				values = append(values, s.Values[i])
+			}
+		}
+	}
+	for _, s := range append(append([]svgSeries(nil), c.Lines...), c.Points...) {
+		if s.X != nil {
+			values = append(values, s.Values...)
+		}
+	}
+	return bounds(values)
+}
+
+func bounds(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	min, max := values[0], values[0]
+	for _, v := range values {
+		min, max = math.Min(min, v), math.Max(max, v)
+	}
+	return min, max
+}
+
+// niceTicks gives evenly spaced round tick values covering the range, and the range extended to the ticks
+func niceTicks(min, max float64, count int) ([]float64, float64, float64) {
+	if max <= min {
+		max = min + 1
+	}
+	raw := (max - min) / float64(count)
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := 10 * magnitude
+	for _, m := range []float64{1, 2, 5} {
+		if raw <= m*magnitude {
+			step = m * magnitude
+			break
+		}
+	}
+	min, max = math.Floor(min/step)*step, math.Ceil(max/step)*step
+	var ticks []float64
+	for v := min; v <= max+step/2; v += step {
+		ticks = append(ticks, v)
+	}
+	return ticks, min, max
+}
+
+// numberTicks labels the X axis with round numbers
+func numberTicks(min, max float64) ([]float64, func(float64) string) {
+	ticks, _, _ := niceTicks(min, max, 10)
+	var inRange []float64
+	for _, t := range ticks {
+		if t >= min && t <= max {
+			inRange = append(inRange, t)
+		}
+	}
+	return inRange, formatNumber
+}
+
+// dateTicks labels the X axis of unix timestamps with the days
+func dateTicks(min, max float64) ([]float64, func(float64) string) {
+	from, to := time.Unix(int64(min), 0), time.Unix(int64(max), 0)
+	step := int(math.Ceil(to.Sub(from).Hours() / 24 / 10))
+	if step < 1 {
+		step = 1
+	}
+	y, m, d := from.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, from.Location())
+	if day.Before(from) {
+		day = day.AddDate(0, 0, 1)
+	}
+	var ticks []float64
+	for ; !day.After(to); day = day.AddDate(0, 0, step) {
+		ticks = append(ticks, float64(day.Unix()))
+	}
+	return ticks, func(v float64) string {
+		return time.Unix(int64(v), 0).Format("Jan 2")
+	}
+}
+
+func formatNumber(v float64) string {
+	return fmt.Sprintf("%g", math.Round(v*100)/100)
+}
+
+func esc(s string) string {
+	return template.HTMLEscapeString(s)
+}
+
+func (d diagram) printSVG(w io.Writer) error {
+	c := svgChart{
+		Title:  "Sprint Burndown (remaning effort) - " + d.Sprint.Name,
+		XTitle: "Days",
+		YTitle: d.Label,
+		XTicks: dateTicks,
+	}
+	c.Stacked = newStackedSeries(d.Buckets)
+	c.Lines = []svgSeries{{Name: "Scope change"}}
+	for _, e := range d.Entries {
+		if e.Time.IsZero() {
+			continue
+		}
+		c.X = append(c.X, float64(e.Time.Unix()))
+		c.addValues(e.Remaining, e.Scope, d.Unit)
+	}
+	if d.StartLine {
+		c.Markers = append(c.Markers, svgMarker{float64(d.Sprint.StartDate.Unix()), "Sprint start"})
+	}
+	if d.Sprint.EndDate != nil {
+		c.Markers = append(c.Markers, svgMarker{float64(d.Sprint.EndDate.Unix()), "Sprint end"})
+	}
+	c.addGuideline(d.Ideal, d.Unit, func(p idealPoint) float64 { return float64(p.Time.Unix()) })
+	t := `
+	   <div id="chart_div" style="width: 100%;">{{ chart }}</div>
+	   <p>Sprint start: {{ .Sprint.StartDate }}</p>
+	   <p>Sprint end: {{ .Sprint.EndDate }}</p>
+	   Generated: {{now}}`
+	return printSVGPage(w, t, c, template.FuncMap{}, d)
+}
+
+func (d hoursDiagram) printSVG(w io.Writer) error {
+	c := svgChart{
+		Title:  "Sprint Burndown (remaning effort) - " + d.Sprint.Name,
+		XTitle: "Sprint work hours",
+		YTitle: d.Label,
+		XTicks: numberTicks,
+	}
+	c.Stacked = newStackedSeries(d.Buckets)
+	c.Lines = []svgSeries{{Name: "Scope change"}}
+	for _, e := range d.Entries {
+		c.X = append(c.X, e.Time.Hours())
+		c.addValues(e.Remaining, e.Scope, d.Unit)
+	}
+	if d.StartLine {
+		c.Markers = append(c.Markers, svgMarker{0, "Sprint start"})
+	}
+	if d.Sprint.EndDate != nil {
+		c.Markers = append(c.Markers, svgMarker{d.sprintWorkHours(*d.Sprint.EndDate), "Sprint end"})
+	}
+	c.addGuideline(d.Ideal, d.Unit, func(p idealPoint) float64 { return p.Hours.Hours() })
+	t := `
+	   <div id="workHours" style="width: 100%;">{{ chart }}</div>
+	   <p>Sprint start: {{ .Sprint.StartDate }}</p>
+	   <p>Sprint end: {{ .Sprint.EndDate }}</p>
+	   <p>Total hours in Sprint: {{ convSprintWorkHours .Sprint.EndDate }}</p>
+	   <p>Work Hours: {{ workDayHours }}</p>
+	   Generated: {{now}}`
+	return printSVGPage(w, t, c, template.FuncMap{
+		"convSprintWorkHours": d.sprintWorkHours,
+		"workDayHours":        d.workDayHours,
+	}, d)
+}
+
+func newStackedSeries(names []string) []svgSeries {
+	series := make([]svgSeries, 0, len(names))
+	for _, n := range names {
+		series = append(series, svgSeries{Name: n})
+	}
+	return series
+}
+
+// addValues appends the bucket values to the stacked series and the scope to the line
+func (c *svgChart) addValues(remaining []int, scope int, unit int) {
+	for i, v := range remaining {
+		c.Stacked[i].Values = append(c.Stacked[i].Values, float64(v)/float64(unit))
+	}
+	c.Lines[0].Values = append(c.Lines[0].Values, float64(scope)/float64(unit))
+}
+
+// addGuideline adds the ideal burndown as a dashed line, x gives the X value of each point
+func (c *svgChart) addGuideline(ideal []idealPoint, unit int, x func(idealPoint) float64) {
+	if len(ideal) == 0 {
+		return
+	}
+	line := svgSeries{Name: "Ideal", Dashed: true, X: []float64{}}
+	for _, p := range ideal {
+		line.X = append(line.X, x(p))
+		line.Values = append(line.Values, float64(p.Value)/float64(unit))
+	}
+	c.Lines = append(c.Lines, line)
+}
+
+// printSVGPage executes the page template with the chart available as 'chart'
+func printSVGPage(w io.Writer, t string, c interface{ render(io.Writer) error }, funcs template.FuncMap, data interface{}) error {
+	var chart strings.Builder
+	err := c.render(&chart)
+	if err != nil {
+		return err
+	}
+	funcs["now"] = time.Now
+	funcs["chart"] = func() template.HTML {
+		return template.HTML(chart.String())
+	}
+	tpl, err := template.New("t").Funcs(funcs).Parse(t)
+	if err != nil {
+		return err
+	}
+	return tpl.Execute(w, data)
+}
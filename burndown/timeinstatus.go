@@ -0,0 +1,309 @@
+package burndown
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	agile "reports/jira"
+	"time"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// TimeInStatus is the time the issues of a sprint or of a JQL query spent in each status during the report,
+// in wall-clock hours and in work hours of the board
+type TimeInStatus struct {
+	// Sprint is the reported sprint, or a sprint without ID spanning the time range
+	Sprint jira.Sprint
+	Board  agile.BoardInfo
+	opts   Opts
+	end    time.Time
+	// statuses are the statuses the issues were in, in order of appearance
+	statuses []string
+	issues   []issueStatuses
+	renderer
+}
+
+// issueStatuses are the periods of an issue in each status, oldest first
+type issueStatuses struct {
+	key     string
+	periods []statusPeriod
+}
+type statusPeriod struct {
+	status   string
+	from, to time.Time
+}
+
+// GenerateTimeInStatus replays the status changes of the issues in the sprint or the time range of opts.
+// Issues created after the start are counted from their creation.
+func GenerateTimeInStatus(ctx context.Context, opts Opts) (*TimeInStatus, error) {
+	err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+	r := &TimeInStatus{}
+	r.renderer = renderer{r}
+	sc, err := opts.scope(ctx)
+	if err != nil {
+		return nil, err
+	}
+	opts.JQL = sc.jql
+	r.Sprint, r.Board, r.opts, r.end = sc.sprint, sc.board, opts, sc.end
+	err = opts.searchScope(ctx, sc, members(r.collect))
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *TimeInStatus) collect(i jira.Issue) {
+	transitions := statusTransitions(i)
+	status := i.Fields.Status.Name
+	if len(transitions) > 0 {
+		status = transitions[0].from
+	}
+	is := issueStatuses{key: i.Key}
+	since := time.Time(i.Fields.Created)
+	for _, t := range transitions {
+		r.addPeriod(&is, status, since, t.time)
+		status, since = t.to, t.time
+	}
+	r.addPeriod(&is, status, since, r.end)
+	if len(is.periods) > 0 {
+		r.issues = append(r.issues, is)
+	}
+}
+
+// addPeriod adds the time in the status, clipped to the report
+func (r *TimeInStatus) addPeriod(is *issueStatuses, status string, from, to time.Time) {
+	if start := *r.Sprint.StartDate; from.Before(start) {
+		from = start
+	}
+	if to.After(r.end) {
+		to = r.end
+	}
+	if !to.After(from) {
+		return
+	}
+	if !contains(r.statuses, status) {
+		r.statuses = append(r.statuses, status)
+	}
+	is.periods = append(is.periods, statusPeriod{status, from, to})
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// statusTime is the time in a status in hours
+type statusTime struct {
+	Hours, WorkHours float64
+}
+
+func (r *TimeInStatus) workHours(p statusPeriod) float64 {
+	return workConverter(r.opts, r.Board, p.from).toSprintWorkTime(p.from, p.to).Hours()
+}
+
+type statusRow struct {
+	Key string
+	// Status is the status at the end of the report
+	Status string
+	// Times are by status, in the order of the report statuses
+	Times []statusTime
+}
+
+func (r *TimeInStatus) rows() []statusRow {
+	var rows []statusRow
+	for _, is := range r.issues {
+		row := statusRow{Key: is.key, Status: is.periods[len(is.periods)-1].status, Times: make([]statusTime, len(r.statuses))}
+		for _, p := range is.periods {
+			for s, name := range r.statuses {
+				if name == p.status {
+					row.Times[s].Hours += p.to.Sub(p.from).Hours()
+					row.Times[s].WorkHours += r.workHours(p)
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// statusTotal is the time all issues spent in a status
+type statusTotal struct {
+	Status string
+	// Issues is the number of issues that were in the status
+	Issues int
+	statusTime
+	// MeanWorkHours is the mean work hours of the issues that were in the status
+	MeanWorkHours float64
+}
+
+func (r *TimeInStatus) totals(rows []statusRow) []statusTotal {
+	totals := make([]statusTotal, len(r.statuses))
+	for s, name := range r.statuses {
+		totals[s].Status = name
+		for _, row := range rows {
+			t := row.Times[s]
+			if t.Hours == 0 {
+				continue
+			}
+			totals[s].Issues++
+			totals[s].Hours += t.Hours
+			totals[s].WorkHours += t.WorkHours
+		}
+		if totals[s].Issues > 0 {
+			totals[s].MeanWorkHours = totals[s].WorkHours / float64(totals[s].Issues)
+		}
+	}
+	return totals
+}
+
+func (r *TimeInStatus) writeHTML(w io.Writer) error {
+	rows := r.rows()
+	tpl, err := template.New("t").Funcs(template.FuncMap{
+		"now": time.Now,
+		"hours": func(v float64) string {
+			return fmt.Sprintf("%.1f", v)
+		},
+	}).Parse(`
+	<h1>Time in status - {{ .Name }}</h1>
+	<p>From: {{ .From }}</p>
+	<p>To: {{ .To }}</p>
+	<p>Work hours: {{ .WorkdayStart }}:00 - {{ .WorkdayEnd }}:00</p>
+	<h2>Statuses</h2>
+	<table>
+	<tr><th>Status</th><th>Issues</th><th>Hours</th><th>Work hours</th><th>Mean work hours per issue</th></tr>
+	{{ range .Totals }}
+	<tr><td>{{ .Status }}</td><td>{{ .Issues }}</td><td>{{ hours .Hours }}</td><td>{{ hours .WorkHours }}</td><td>{{ hours .MeanWorkHours }}</td></tr>{{ end }}
+	</table>
+	<h2>Issues</h2>
+	<p>Hours / work hours in each status</p>
+	<table>
+	<tr><th>Issue</th><th>Status</th>{{ range .Statuses }}<th>{{ . }}</th>{{ end }}</tr>
+	{{ range .Rows }}
+	<tr><td>{{ .Key }}</td><td>{{ .Status }}</td>{{ range .Times }}<td>{{ if .Hours }}{{ hours .Hours }} / {{ hours .WorkHours }}{{ end }}</td>{{ end }}</tr>{{ end }}
+	</table>
+	Generated: {{ now }}`)
+	if err != nil {
+		return err
+	}
+	return tpl.Execute(w, struct {
+		Name                     string
+		From, To                 time.Time
+		WorkdayStart, WorkdayEnd int
+		Statuses                 []string
+		Totals                   []statusTotal
+		Rows                     []statusRow
+	}{r.Sprint.Name, *r.Sprint.StartDate, r.end, r.opts.WorkdayStart, r.opts.WorkdayEnd, r.statuses, r.totals(rows), rows})
+}
+
+// timeInStatusExport is the JSON document of the time in status, times are in hours
+type timeInStatusExport struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	Generated     time.Time `json:"generated"`
+	// Sprint is null for a time range
+	Sprint      *exportSprint  `json:"sprint"`
+	JQL         string         `json:"jql,omitempty"`
+	From        time.Time      `json:"from"`
+	To          time.Time      `json:"to"`
+	WorkingTime exportWorkTime `json:"workingTime"`
+	// Statuses are the totals of all issues by status, in order of appearance
+	Statuses []exportStatusTotal `json:"statuses"`
+	Issues   []exportIssueStatus `json:"issues"`
+	// Entries are the periods of the issues in a status, valued in work hours
+	Entries []exportEntry `json:"entries"`
+}
+type exportStatusTime struct {
+	Status    string  `json:"status"`
+	Hours     float64 `json:"hours"`
+	WorkHours float64 `json:"workHours"`
+}
+type exportStatusTotal struct {
+	exportStatusTime
+	Issues        int     `json:"issues"`
+	MeanWorkHours float64 `json:"meanWorkHours"`
+}
+type exportIssueStatus struct {
+	Key string `json:"key"`
+	// Status is the status at the end of the report
+	Status string `json:"status"`
+	// Times are the statuses the issue was in
+	Times []exportStatusTime `json:"times"`
+}
+
+func (r *TimeInStatus) export() exporter {
+	start := *r.Sprint.StartDate
+	opts := r.opts
+	opts.FullTimeline = false
+	e := timeInStatusExport{
+		SchemaVersion: schemaVersion,
+		Generated:     time.Now(),
+		JQL:           r.opts.JQL,
+		From:          start,
+		To:            r.end,
+		WorkingTime:   exportWorkTimeOf(opts, r.Board, start),
+		Statuses:      []exportStatusTotal{},
+		Issues:        []exportIssueStatus{},
+		Entries:       []exportEntry{},
+	}
+	if r.opts.JQL == "" {
+		s := exportSprintOf(r.Sprint)
+		e.Sprint = &s
+	}
+	rows := r.rows()
+	for _, t := range r.totals(rows) {
+		e.Statuses = append(e.Statuses, exportStatusTotal{exportStatusTime{t.Status, t.Hours, t.WorkHours}, t.Issues, t.MeanWorkHours})
+	}
+	for _, row := range rows {
+		is := exportIssueStatus{Key: row.Key, Status: row.Status, Times: []exportStatusTime{}}
+		for s, t := range row.Times {
+			if t.Hours > 0 {
+				is.Times = append(is.Times, exportStatusTime{r.statuses[s], t.Hours, t.WorkHours})
+			}
+		}
+		e.Issues = append(e.Issues, is)
+	}
+	for _, is := range r.issues {
+		for _, p := range is.periods {
+			msg := fmt.Sprintf("%s: in %s until %s", is.key, p.status, p.to.Format(time.RFC3339))
+			e.Entries = append(e.Entries, exportEntry{is.key, exportTime(p.from), r.workHours(p), msg})
+		}
+	}
+	return e
+}
+
+// writeCSV writes the time of the issues with columns: key, status, hours, work_hours.
+// The totals of all issues are last with the key empty.
+func (e timeInStatusExport) writeCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"key", "status", "hours", "work_hours"})
+	for _, i := range e.Issues {
+		for _, t := range i.Times {
+			c.Write([]string{i.Key, t.Status, formatFloat(t.Hours), formatFloat(t.WorkHours)})
+		}
+	}
+	for _, t := range e.Statuses {
+		c.Write([]string{"", t.Status, formatFloat(t.Hours), formatFloat(t.WorkHours)})
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// writeEntriesCSV writes the periods in a status with columns: list (issue key), time, value (work hours), message
+func (e timeInStatusExport) writeEntriesCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"list", "time", "value", "message"})
+	for _, v := range e.Entries {
+		c.Write([]string{v.Bucket, csvTime(v.Time), formatFloat(v.Value), v.Message})
+	}
+	c.Flush()
+	return c.Error()
+}
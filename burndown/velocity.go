@@ -0,0 +1,297 @@
+package burndown
+
+import (
+	"context"
+	"encoding/csv"
+	"html/template"
+	"io"
+	agile "reports/jira"
+	"sort"
+	"strconv"
+	"time"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// Velocity is the committed and completed effort of the last closed sprints of a board.
+// Committed is the remaining effort at sprint start, completed is the effort of the issues
+// moved to a done status between sprint start and completion.
+type Velocity struct {
+	Board    agile.BoardInfo
+	opts     Opts
+	estimate estimator
+	sprints  []sprintVelocity
+	renderer
+}
+type sprintVelocity struct {
+	sprint               jira.Sprint
+	committed, completed int
+	// completions are the completed and reopened issues during the sprint
+	completions []entry
+}
+
+// GenerateVelocity collects the velocity of the last closed sprints of the board of opts, oldest first.
+// The sprint of opts is not used.
+func GenerateVelocity(ctx context.Context, opts Opts, last int) (*Velocity, error) {
+	err := opts.validate()
+	if err != nil {
+		return nil, err
+	}
+	if last < 1 {
+		return nil, &OptionError{"number of sprints", last, "at least 1"}
+	}
+	board, err := opts.Client.GetScrumBoardID(ctx, opts.Board, opts.BoardFilter, opts.Interactive)
+	if err != nil {
+		return nil, &StepError{"resolve board", err}
+	}
+	sprints, err := opts.Client.GetSprints(ctx, board, "closed")
+	if err != nil {
+		return nil, &StepError{"get closed sprints", err}
+	}
+	sprints = lastSprints(sprints, last)
+	bi, err := opts.Client.GetBoardInfo(ctx, board)
+	if err != nil {
+		return nil, &StepError{"get board configuration", err}
+	}
+	mapping, err := opts.statusMapping(ctx, bi)
+	if err != nil {
+		return nil, err
+	}
+	v := &Velocity{Board: bi, opts: opts}
+	v.renderer = renderer{v}
+	v.estimate, err = newEstimator(opts.Metric, bi)
+	if err != nil {
+		return nil, &StepError{"select metric", err}
+	}
+	for _, s := range sprints {
+		if s.StartDate == nil {
+			//Closed without being started, nothing was committed
+			continue
+		}
+		d := &data{
+			start:    *s.StartDate,
+			in:       sprintContainer(s.ID),
+			statuses: mapping.classifier(),
+			estimate: v.estimate,
+		}
+		d.buckets = make([][]entry, len(d.statuses.names))
+		err = searchSprintIssues(ctx, opts.Client, s, d.start, d.collector)
+		if err != nil {
+			return nil, err
+		}
+		v.sprints = append(v.sprints, d.velocity(s))
+	}
+	return v, nil
+}
+
+// lastSprints gives the last n sprints by completion, oldest first
+func lastSprints(sprints []jira.Sprint, n int) []jira.Sprint {
+	sorted := append([]jira.Sprint(nil), sprints...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sprintEnd(sorted[i]).Before(sprintEnd(sorted[j]))
+	})
+	if len(sorted) > n {
+		sorted = sorted[len(sorted)-n:]
+	}
+	return sorted
+}
+
+// sprintEnd gives the completion time of the sprint, or the planned end if not completed
+func sprintEnd(s jira.Sprint) time.Time {
+	switch {
+	case s.CompleteDate != nil:
+		return *s.CompleteDate
+	case s.EndDate != nil:
+		return *s.EndDate
+	}
+	return time.Time{}
+}
+
+func (d *data) velocity(s jira.Sprint) sprintVelocity {
+	v := sprintVelocity{sprint: s, committed: committed(d.collapse(d.start), d.start)}
+	end := sprintEnd(s)
+	sortByTime(d.done)
+	for _, e := range d.done {
+		if !end.IsZero() && e.Time.After(end) {
+			break
+		}
+		v.completed += e.Value
+		v.completions = append(v.completions, e)
+	}
+	return v
+}
+
+// averageCompleted gives the mean completed effort of the sprints
+func (v *Velocity) averageCompleted() float64 {
+	if len(v.sprints) == 0 {
+		return 0
+	}
+	var total int
+	for _, s := range v.sprints {
+		total += s.completed
+	}
+	return v.estimate.display(total) / float64(len(v.sprints))
+}
+
+type velocityRow struct {
+	Sprint               jira.Sprint
+	Committed, Completed float64
+}
+
+func (v *Velocity) rows() []velocityRow {
+	var rows []velocityRow
+	for _, s := range v.sprints {
+		rows = append(rows, velocityRow{s.sprint, v.estimate.display(s.committed), v.estimate.display(s.completed)})
+	}
+	return rows
+}
+
+func (v *Velocity) writeHTML(w io.Writer) error {
+	page := struct {
+		Board   string
+		Unit    string
+		Rows    []velocityRow
+		Average float64
+	}{v.Board.Name, v.estimate.name, v.rows(), v.averageCompleted()}
+	var err error
+	if v.opts.Renderer == SVG {
+		c := svgBarChart{
+			Title:  "Velocity - " + v.Board.Name,
+			YTitle: v.estimate.name,
+			Series: []svgSeries{{Name: "Committed"}, {Name: "Completed"}},
+		}
+		for _, r := range page.Rows {
+			c.Categories = append(c.Categories, r.Sprint.Name)
+			c.Series[0].Values = append(c.Series[0].Values, r.Committed)
+			c.Series[1].Values = append(c.Series[1].Values, r.Completed)
+		}
+		err = printSVGPage(w, `<div id="velocity" style="width: 100%;">{{ chart }}</div>`, c, template.FuncMap{}, page)
+	} else {
+		err = printHeader(w)
+		if err != nil {
+			return err
+		}
+		err = executeTemplate(w, `
+	<div id="velocity" style="width: 100%; height: 500px;"></div>
+	<script>
+	google.charts.load('current', {'packages':['corechart']});
+	google.charts.setOnLoadCallback(drawChart);
+
+	function drawChart() {
+		var data = google.visualization.arrayToDataTable([
+			['Sprint', 'Committed', 'Completed'],
+			{{ range .Rows }}[{{ .Sprint.Name }}, {{ .Committed }}, {{ .Completed }}],
+			{{ end }}
+		]);
+		var options = {
+			title: {{ printf "Velocity - %s" .Board }},
+			vAxis: {title: {{ .Unit }}, minValue: 0}
+		};
+		var chart = new google.visualization.ColumnChart(document.getElementById('velocity'));
+		chart.draw(data, options);
+	}
+	</script>`, page)
+	}
+	if err != nil {
+		return err
+	}
+	err = executeTemplate(w, `
+	<h2>Sprints</h2>
+	<table>
+	<tr><th>Sprint</th><th>Start</th><th>Completed</th><th>Committed {{ .Unit }}</th><th>Completed {{ .Unit }}</th></tr>
+	{{ range .Rows }}
+	<tr><td>{{ .Sprint.Name }}</td><td>{{ .Sprint.StartDate }}</td><td>{{ .Sprint.CompleteDate }}</td><td>{{ .Committed }}</td><td>{{ .Completed }}</td></tr>{{ end }}
+	</table>
+	<p>Average completed: {{ printf "%.1f" .Average }}</p>
+	Generated: {{ now }}`, page)
+	if err != nil {
+		return err
+	}
+	for _, s := range v.sprints {
+		err = printTable(w, s.sprint.Name, s.completions, v.estimate)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// executeTemplate parses and executes the HTML template with the 'now' function
+func executeTemplate(w io.Writer, t string, data interface{}) error {
+	tpl, err := template.New("t").Funcs(template.FuncMap{"now": time.Now}).Parse(t)
+	if err != nil {
+		return err
+	}
+	return tpl.Execute(w, data)
+}
+
+// velocityExport is the JSON document of the velocity, effort values are in the unit of the metric
+type velocityExport struct {
+	SchemaVersion int              `json:"schemaVersion"`
+	Generated     time.Time        `json:"generated"`
+	Board         exportBoard      `json:"board"`
+	Metric        exportMetric     `json:"metric"`
+	Sprints       []exportVelocity `json:"sprints"`
+	// AverageCompleted is the mean completed effort of the sprints
+	AverageCompleted float64 `json:"averageCompleted"`
+}
+type exportBoard struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+type exportVelocity struct {
+	Sprint    exportSprint `json:"sprint"`
+	Committed float64      `json:"committed"`
+	Completed float64      `json:"completed"`
+	// Entries are the issues completed, or reopened with negative value, during the sprint
+	Entries []exportEntry `json:"entries"`
+}
+
+func (v *Velocity) export() exporter {
+	metric := v.opts.Metric
+	if metric == "" {
+		metric = RemainingTime
+	}
+	e := velocityExport{
+		SchemaVersion:    schemaVersion,
+		Generated:        time.Now(),
+		Board:            exportBoard{v.Board.ID, v.Board.Name},
+		Metric:           exportMetric{metric, v.estimate.name},
+		Sprints:          []exportVelocity{},
+		AverageCompleted: v.averageCompleted(),
+	}
+	d := data{estimate: v.estimate}
+	for _, s := range v.sprints {
+		e.Sprints = append(e.Sprints, exportVelocity{
+			Sprint:    exportSprintOf(s.sprint),
+			Committed: v.estimate.display(s.committed),
+			Completed: v.estimate.display(s.completed),
+			Entries:   d.exportEntries("", s.completions),
+		})
+	}
+	return e
+}
+
+// writeCSV writes the sprints with columns: sprint_id, sprint, start, complete, committed, completed
+func (e velocityExport) writeCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"sprint_id", "sprint", "start", "complete", "committed", "completed"})
+	for _, s := range e.Sprints {
+		c.Write([]string{strconv.Itoa(s.Sprint.ID), s.Sprint.Name, csvTime(s.Sprint.StartDate), csvTime(s.Sprint.CompleteDate), formatFloat(s.Committed), formatFloat(s.Completed)})
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// writeEntriesCSV writes the completed issues with columns: list (sprint name), time, value, message
+func (e velocityExport) writeEntriesCSV(w io.Writer) error {
+	c := csv.NewWriter(w)
+	c.Write([]string{"list", "time", "value", "message"})
+	for _, s := range e.Sprints {
+		for _, v := range s.Entries {
+			c.Write([]string{s.Sprint.Name, csvTime(v.Time), formatFloat(v.Value), v.Message})
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"html/template"
 	"io"
-	"log"
 	info "reports/jira"
 	"time"
 
@@ -16,11 +15,16 @@ type hoursDiagram struct {
 	Entries   []sprintHoursEntry
 	StartLine bool
 	WorkInfo  converter
+	Unit      int
+	Label     string
+	Buckets   []string
+	Ideal     []idealPoint
 }
 type sprintHoursEntry struct {
-	Time     time.Duration
-	New      int
-	Progress int
+	Time time.Duration
+	// Remaining effort in each bucket
+	Remaining []int
+	Scope     int
 }
 
 //Converter converts timestamps to sprint working time (duration from sprint start)
@@ -41,7 +45,8 @@ func (d data) prepareWorkHoursDiagram(s jira.Sprint, startTime time.Time, startM
 	e := conv.convertToSprintHoursEntries(sum)
 	//As conversion may have created duplicate entries for the same time, eliminate these
 	e = dedupeHours(e)
-	return hoursDiagram{s, e, startMargin, conv}
+	ideal := conv.guideline(s, committed(sum, *s.StartDate))
+	return hoursDiagram{s, e, startMargin, conv, d.estimate.unit, d.estimate.label(), d.statuses.names, ideal}
 }
 
 func dedupeHours(e []sprintHoursEntry) []sprintHoursEntry {
@@ -61,15 +66,15 @@ func (hd converter) convertToSprintHoursEntries(e []tableEntry) []sprintHoursEnt
 	result := make([]sprintHoursEntry, 0, len(e))
 	for _, v := range e {
 		result = append(result, sprintHoursEntry{
-			Time:     hd.toSprintWorkTime(hd.Start, v.Time),
-			New:      v.New,
-			Progress: v.Progress,
+			Time:      hd.toSprintWorkTime(hd.Start, v.Time),
+			Remaining: v.Remaining,
+			Scope:     v.Scope,
 		})
 	}
 	return result
 }
 
-func (d hoursDiagram) printDiagram(w io.Writer) {
+func (d hoursDiagram) printDiagram(w io.Writer) error {
 	t := `
 	   <div id="workHours" style="width: 100%; height: 500px;"></div>
 	   <p>Sprint start: {{ .Sprint.StartDate }}</p>
@@ -85,51 +90,60 @@ func (d hoursDiagram) printDiagram(w io.Writer) {
 	var data = new google.visualization.DataTable();
 	data.addColumn('number', 'Time');
 	data.addColumn({type:'string', role:'annotation'});
-	data.addColumn('number', 'New');
-	data.addColumn('number', 'In Progress');
+	{{ range .Buckets }}data.addColumn('number', {{ . }});
+	{{ end }}data.addColumn('number', 'Scope change');
+	data.addColumn('number', 'Ideal');
 	data.addRows([
 		{{ range .Entries }}
-		[{{ SprintWorkHours .Time }}, null, {{ .New }}/3600,{{ .Progress}}/3600],{{ end }}
-		{{ if .StartLine }}[0, "Sprint start",null,null],{{end}}
-		{{ if .Sprint.EndDate }}[{{ convSprintWorkHours .Sprint.EndDate }}, "Sprint end",null,null],{{end}}
-		[null,null,null,null]
+		[{{ SprintWorkHours .Time }}, null, {{ range .Remaining }}{{ . }}/{{ $.Unit }},{{ end }}{{ .Scope }}/{{ $.Unit }},null],{{ end }}
+		{{ range .Ideal }}
+		[{{ SprintWorkHours .Hours }}, null, {{ range $.Buckets }}null,{{ end }}null,{{ .Value }}/{{ $.Unit }}],{{ end }}
+		{{ if .StartLine }}[0, "Sprint start",{{ range .Buckets }}null,{{ end }}null,null],{{end}}
+		{{ if .Sprint.EndDate }}[{{ convSprintWorkHours .Sprint.EndDate }}, "Sprint end",{{ range .Buckets }}null,{{ end }}null,null],{{end}}
+		[null,null,{{ range .Buckets }}null,{{ end }}null,null]
 	]);
 
 		var options = {
 			title: 'Sprint Burndown (remaning effort) - {{ .Sprint.Name }}',
 			hAxis: {title: 'Sprint work hours',  titleTextStyle: {color: '#333'}},
-			vAxis: {title: 'Hours remaining', minValue: 0},
+			vAxis: {title: {{ .Label }}, minValue: 0},
 			isStacked: true,
+			seriesType: 'area',
+			series: {{ lineSeries .Buckets }},
+			interpolateNulls: true,
 			annotations: {style:'line'}
 		};
 
-		var chart = new google.visualization.AreaChart(document.getElementById('workHours'));
+		var chart = new google.visualization.ComboChart(document.getElementById('workHours'));
 		chart.draw(data, options);
 	}
 	</script>`
 	tpl, err := template.New("t").Funcs(template.FuncMap{
-		"now": time.Now,
+		"now":        time.Now,
+		"lineSeries": lineSeries,
 		"SprintWorkHours": func(t time.Duration) float64 {
 			return t.Seconds() / 3600
 		},
-		"convSprintWorkHours": func(t time.Time) float64 {
-			return d.WorkInfo.toSprintWorkTime(*d.Sprint.StartDate, t).Seconds() / 3600
-		},
+		"convSprintWorkHours": d.sprintWorkHours,
 		// "nice": func(secs float32) string {
 		// 	return fmt.Sprintf("%.2f", secs/3600)
 		// },
-		"workDayHours": func() string {
-			return fmt.Sprintf("%02d:00 - %02d:00", d.WorkInfo.WorkDayStartHours, d.WorkInfo.WorkDayEndHours)
-		},
+		"workDayHours": d.workDayHours,
 	}).Parse(t)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	err = tpl.Execute(w, d)
-	if err != nil {
-		log.Fatal(err)
-	}
+	return tpl.Execute(w, d)
+}
+
+// sprintWorkHours gives the work hours from sprint start to the given time
+func (d hoursDiagram) sprintWorkHours(t time.Time) float64 {
+	return d.WorkInfo.toSprintWorkTime(*d.Sprint.StartDate, t).Seconds() / 3600
+}
+
+func (d hoursDiagram) workDayHours() string {
+	return fmt.Sprintf("%02d:00 - %02d:00", d.WorkInfo.WorkDayStartHours, d.WorkInfo.WorkDayEndHours)
 }
 
 func (hd converter) toSprintWorkTime(start, t time.Time) time.Duration {
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var listJSON bool
+
+func init() {
+	addBoardFilterFlags(boardsCmd)
+	boardsCmd.Flags().BoolVar(&listJSON, "json", listJSON, "Print JSON instead of a table.")
+	rootCmd.AddCommand(boardsCmd)
+}
+
+var boardsCmd = &cobra.Command{
+	Use:          "boards",
+	Short:        "List the boards with their IDs.",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := commandContext()
+		defer cancel()
+		boards, err := c.GetBoards(ctx, boardFilter())
+		if err != nil {
+			return err
+		}
+		var rows [][]string
+		for _, b := range boards {
+			rows = append(rows, []string{fmt.Sprint(b.ID), b.Name, b.Type, fmt.Sprint(b.FilterID)})
+		}
+		return printList(cmd.OutOrStdout(), boards, []string{"ID", "NAME", "TYPE", "FILTER"}, rows)
+	},
+}
+
+// printList prints the values as JSON with --json, otherwise the rows as a table
+func printList(w io.Writer, values interface{}, header []string, rows [][]string) error {
+	if listJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(values)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, r := range rows {
+		fmt.Fprintln(tw, strings.Join(r, "\t"))
+	}
+	return tw.Flush()
+}
@@ -1,77 +1,204 @@
 package cmd
 
 import (
+	"fmt"
 	"log"
 	"path/filepath"
 	"reports/burndown"
 	"reports/jira"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	sprints                   []string
-	board, output             string
+	sprints, formats          []string
+	board, output, metric     string
+	project, boardType        string
+	renderer, statusMap       string
 	startMargin, fullTimeline bool
+	boardColumns, byAssignee  bool
 	workdayStart, workdayEnd  = 10, 18
 )
 
 func init() {
-	burndownCmd.Flags().StringArrayVarP(&sprints, "sprint", "s", nil, "Name or ID of the sprint to get the report for. Default gets the first active sprint. If mutiple are provided, the output filenames are in the format {sprintID}-{output}.")
-	burndownCmd.Flags().StringVarP(&board, "board", "b", "", "Name or ID of the Sprint board to use.")
-	burndownCmd.Flags().StringVarP(&output, "output", "o", "estimates-burndown.html", "Name of the file to write the HTML report.")
+	addSprintFlag(burndownCmd)
+	addBoardFlags(burndownCmd, "Name or ID of the board to use, with --from or --last also a kanban board.")
+	addTimeRangeFlags(burndownCmd)
+	addOutputFlag(burndownCmd, &output, "estimates-burndown.html")
 	burndownCmd.Flags().BoolVar(&startMargin, "start-margin", startMargin, "add additional 1 day margin before the sprint start")
-	burndownCmd.Flags().BoolVar(&fullTimeline, "full-timeline", fullTimeline, "Do not strip chart to working time only. Also show weekends and non-work time in the chart.")
-	burndownCmd.Flags().IntVar(&workdayStart, "workday-start", workdayStart, "When does the working day start (0-23). This is ignored in full-timeline mode.")
-	burndownCmd.Flags().IntVar(&workdayEnd, "workday-end", workdayEnd, "When does the working day end (0-23). This is ignored in full-timeline mode.")
+	addWorkTimeFlags(burndownCmd, fullTimelineUsage)
+	addFormatFlags(burndownCmd, true)
+	addStatusMapFlag(burndownCmd, "the stacked chart buckets")
+	burndownCmd.Flags().BoolVar(&boardColumns, "board-columns", boardColumns, "Stack the board columns in the chart, counting the last column as done. Cannot be used with --status-map.")
+	burndownCmd.Flags().BoolVar(&byAssignee, "by-assignee", byAssignee, "Stack the remaining effort by assignee instead of status, with tables of the changes per person. The status mapping only tells the done statuses.")
+	addMetricFlag(burndownCmd, &metric, burndown.RemainingTime, "burn down")
 	rootCmd.AddCommand(burndownCmd)
 }
 
 var burndownCmd = &cobra.Command{
-	Use:     "burndown",
-	Short:   "Generate the effort estimates burndown report for one or more given sprints.",
-	Aliases: []string{"b"},
-	Run: func(cmd *cobra.Command, args []string) {
-		validateFlags()
-		c := jira.InitJira(user, password, url)
+	Use:          "burndown",
+	Short:        "Generate the effort estimates burndown report for one or more given sprints or a time range.",
+	Aliases:      []string{"b"},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := commandContext()
+		defer cancel()
+		mapping, err := statusMapping()
+		if err != nil {
+			return err
+		}
 		opts := burndown.Opts{
 			Client:       c,
 			Board:        board,
+			BoardFilter:  boardFilter(),
 			Interactive:  interactive,
 			StartMargin:  startMargin,
 			FullTimeline: fullTimeline,
 			WorkdayStart: workdayStart,
 			WorkdayEnd:   workdayEnd,
+			Metric:       burndown.Metric(metric),
+			Renderer:     burndown.Renderer(renderer),
+			Statuses:     mapping,
+			BoardColumns: boardColumns,
+			ByAssignee:   byAssignee,
 		}
-		switch len(sprints) {
-		case 0:
-			opts.Outfile = output
-			burndown.Run(opts)
-			return
-		case 1:
-			opts.Outfile = output
-			opts.Sprint = sprints[0]
-			burndown.Run(opts)
-			return
+		return forEachScope(output, opts, func(opts burndown.Opts, outfile string) error {
+			r, err := burndown.Generate(ctx, opts)
+			if err != nil {
+				return err
+			}
+			return logFiles(r.WriteFiles(outfile, outputFormats()...))
+		})
+	},
+}
+
+// forEachSprint runs the report for each given sprint, or the default sprint if none given.
+// With multiple sprints the output files are prefixed with the sprint and failures are reported at the end.
+func forEachSprint(outfile string, run func(sprint, outfile string) error) error {
+	if len(sprints) <= 1 {
+		var sprint string
+		if len(sprints) == 1 {
+			sprint = sprints[0]
 		}
-		for _, s := range sprints {
-			opts.Outfile = fileNameWithPrefix(output, s+"-")
-			opts.Sprint = s
-			burndown.Run(opts)
+		return run(sprint, outfile)
+	}
+	var failed []string
+	for _, s := range sprints {
+		err := run(s, fileNameWithPrefix(outfile, s+"-"))
+		if err != nil {
+			log.Printf("Sprint %s failed: %v", s, err)
+			failed = append(failed, s)
 		}
-	},
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("reports failed for %d of %d sprints: %s", len(failed), len(sprints), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// addSprintFlag adds --sprint for the reports of one or more sprints, see forEachSprint
+func addSprintFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVarP(&sprints, "sprint", "s", nil, "Name or ID of the sprint to get the report for. Default gets the first active sprint. If multiple are provided, the output filenames are in the format {sprintID}-{output}.")
+}
+
+// addOutputFlag adds --output with the default file name of the command
+func addOutputFlag(cmd *cobra.Command, p *string, value string) {
+	cmd.Flags().StringVarP(p, "output", "o", value, "Name of the file to write the HTML report.")
 }
 
-func validateFlags() {
-	if workdayStart < 0 || 23 < workdayStart {
-		log.Fatalln("Workday start time must be in range 0-23")
+// fullTimelineUsage is the usage of --full-timeline for the reports with a timeline chart
+const fullTimelineUsage = "Do not strip chart to working time only. Also show weekends and non-work time in the chart."
+
+// addWorkTimeFlags adds the working day flags and --full-timeline with the given usage,
+// reports without a full timeline mode give an empty usage
+func addWorkTimeFlags(cmd *cobra.Command, fullTimelineUse string) {
+	var ignored string
+	if fullTimelineUse != "" {
+		cmd.Flags().BoolVar(&fullTimeline, "full-timeline", fullTimeline, fullTimelineUse)
+		ignored = " This is ignored in full-timeline mode."
 	}
-	if workdayEnd < 0 || 23 < workdayEnd {
-		log.Fatalln("Workday end time must be in range 0-23")
+	cmd.Flags().IntVar(&workdayStart, "workday-start", workdayStart, "When does the working day start (0-23)."+ignored)
+	cmd.Flags().IntVar(&workdayEnd, "workday-end", workdayEnd, "When does the working day end (0-23)."+ignored)
+}
+
+// addBoardFilterFlags adds the flags narrowing the board lookup by name
+func addBoardFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&project, "project", "", "Key or ID of the project to look up boards in.")
+	cmd.Flags().StringVar(&boardType, "board-type", "", "Type of the boards to look up: 'scrum', 'kanban' or 'simple'.")
+}
+
+// addBoardFlags adds --board, usage tells what the command uses the board for, and the flags narrowing its lookup
+func addBoardFlags(cmd *cobra.Command, usage string) {
+	cmd.Flags().StringVarP(&board, "board", "b", "", usage)
+	addBoardFilterFlags(cmd)
+}
+
+// workWeekBoardUsage is the usage of --board for the reports not limited to a board
+const workWeekBoardUsage = "Name or ID of the board to take the working days, columns and estimation field from. Default works Monday to Friday."
+
+// addFormatFlags adds the output formats and for reports with charts the renderer
+func addFormatFlags(cmd *cobra.Command, charts bool) {
+	cmd.Flags().StringArrayVarP(&formats, "format", "f", []string{string(burndown.HTML)}, "Output format: 'html', 'json' or 'csv'. Can be repeated. JSON and CSV files are named after the output with the extension replaced.")
+	if charts {
+		cmd.Flags().StringVar(&renderer, "renderer", string(burndown.GoogleCharts), "How to draw the charts: 'google' (Google Charts, requires internet access when viewing) or 'svg' (inline SVG, self-contained file).")
+	}
+}
+
+// addStatusMapFlag adds --status-map, buckets tells how the command uses the buckets of the mapping
+func addStatusMapFlag(cmd *cobra.Command, buckets string) {
+	cmd.Flags().StringVar(&statusMap, "status-map", "", fmt.Sprintf("YAML file mapping statuses to %s. Default uses the status categories.", buckets))
+}
+
+// addMetricFlag adds --metric with the default value, use tells what the command does with the statistic
+func addMetricFlag(cmd *cobra.Command, p *string, value burndown.Metric, use string, metrics ...burndown.Metric) {
+	if len(metrics) == 0 {
+		metrics = []burndown.Metric{burndown.RemainingTime, burndown.StoryPoints, burndown.IssueCount}
+	}
+	var usage []string
+	for _, m := range metrics {
+		usage = append(usage, metricUsage[m])
 	}
-	if workdayEnd < workdayStart {
-		log.Fatalln("Workday start time must be before end time")
+	last := len(usage) - 1
+	cmd.Flags().StringVarP(p, "metric", "m", string(value), fmt.Sprintf("Statistic to %s: %s or %s.", use, strings.Join(usage[:last], ", "), usage[last]))
+}
+
+// metricUsage describes the metrics in the help of --metric
+var metricUsage = map[burndown.Metric]string{
+	burndown.RemainingTime: "'time' (remaining estimate)",
+	burndown.StoryPoints:   "'points' (board estimation field, e.g. story points)",
+	burndown.IssueCount:    "'count' (number of issues)",
+}
+
+func boardFilter() jira.BoardFilter {
+	return jira.BoardFilter{Project: project, Type: boardType}
+}
+
+// statusMapping gives the mapping of --status-map or the config profile, nil for the default mapping
+func statusMapping() (*burndown.StatusMapping, error) {
+	if statusMap != "" {
+		return burndown.LoadStatusMapping(statusMap)
+	}
+	return profileStatuses, nil
+}
+
+func outputFormats() []burndown.Format {
+	var outFormats []burndown.Format
+	for _, f := range formats {
+		outFormats = append(outFormats, burndown.Format(f))
+	}
+	return outFormats
+}
+
+func logFiles(files []string, err error) error {
+	for _, f := range files {
+		log.Println("Report written to: " + f)
 	}
+	return err
 }
 
 func fileNameWithPrefix(file, prefix string) string {
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"reports/burndown"
+
+	"github.com/spf13/cobra"
+)
+
+var burnupOutput string
+
+func init() {
+	addSprintFlag(burnupCmd)
+	addBoardFlags(burnupCmd, "Name or ID of the Sprint board to use.")
+	addOutputFlag(burnupCmd, &burnupOutput, "burnup.html")
+	burnupCmd.Flags().BoolVar(&startMargin, "start-margin", startMargin, "add additional 1 day margin before the sprint start")
+	addWorkTimeFlags(burnupCmd, fullTimelineUsage)
+	addFormatFlags(burnupCmd, true)
+	rootCmd.AddCommand(burnupCmd)
+}
+
+var burnupCmd = &cobra.Command{
+	Use:          "burnup",
+	Short:        "Generate the burn-up report of logged work against total scope for one or more given sprints.",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := commandContext()
+		defer cancel()
+		opts := burndown.Opts{
+			Client:       c,
+			Board:        board,
+			BoardFilter:  boardFilter(),
+			Interactive:  interactive,
+			StartMargin:  startMargin,
+			FullTimeline: fullTimeline,
+			WorkdayStart: workdayStart,
+			WorkdayEnd:   workdayEnd,
+			Renderer:     burndown.Renderer(renderer),
+		}
+		return forEachSprint(burnupOutput, func(sprint, outfile string) error {
+			opts.Sprint = sprint
+			b, err := burndown.GenerateBurnup(ctx, opts)
+			if err != nil {
+				return err
+			}
+			return logFiles(b.WriteFiles(outfile, outputFormats()...))
+		})
+	},
+}
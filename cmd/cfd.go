@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"reports/burndown"
+
+	"github.com/spf13/cobra"
+)
+
+var cfdOutput string
+
+func init() {
+	addSprintFlag(cfdCmd)
+	addBoardFlags(cfdCmd, "Name or ID of the board to use. With --jql only its working days, columns and estimation field are used.")
+	addTimeRangeFlags(cfdCmd)
+	addOutputFlag(cfdCmd, &cfdOutput, "cfd.html")
+	addWorkTimeFlags(cfdCmd, fullTimelineUsage)
+	addFormatFlags(cfdCmd, true)
+	addStatusMapFlag(cfdCmd, "the stacked chart buckets, the done statuses are stacked last")
+	cfdCmd.Flags().BoolVar(&boardColumns, "board-columns", boardColumns, "Stack the board columns in the chart. Cannot be used with --status-map.")
+	addMetricFlag(cfdCmd, &cfdMetric, burndown.IssueCount, "stack", burndown.StoryPoints, burndown.IssueCount)
+	rootCmd.AddCommand(cfdCmd)
+}
+
+var cfdMetric string
+
+var cfdCmd = &cobra.Command{
+	Use:          "cfd",
+	Short:        "Generate the cumulative flow diagram of the issues in each status over a sprint or a time range.",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := commandContext()
+		defer cancel()
+		mapping, err := statusMapping()
+		if err != nil {
+			return err
+		}
+		opts := burndown.Opts{
+			Client:       c,
+			Board:        board,
+			BoardFilter:  boardFilter(),
+			Interactive:  interactive,
+			FullTimeline: fullTimeline,
+			WorkdayStart: workdayStart,
+			WorkdayEnd:   workdayEnd,
+			Metric:       burndown.Metric(cfdMetric),
+			Renderer:     burndown.Renderer(renderer),
+			Statuses:     mapping,
+			BoardColumns: boardColumns,
+		}
+		return forEachScope(cfdOutput, opts, func(opts burndown.Opts, outfile string) error {
+			r, err := burndown.GenerateCFD(ctx, opts)
+			if err != nil {
+				return err
+			}
+			return logFiles(r.WriteFiles(outfile, outputFormats()...))
+		})
+	},
+}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reports/burndown"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// configuration is the YAML config file with named profiles, e.g.
+//
+//	default: work
+//	profiles:
+//	  work:
+//	    url: https://jira.example.com
+//	    auth: bearer
+//	    token-file: ~/.jira-token
+//	    board: Team board
+//	    workday-start: 9
+//	    workday-end: 17
+//	    format: [html, json]
+//	    statuses:
+//	      buckets:
+//	        - name: To Do
+//	          statuses: [Open]
+//	      done: [Done]
+//
+// The profile keys are the long flag names, flags given on the command line and their environment variables
+// override them.
+// The statuses key holds a status mapping like the file of --status-map.
+type configuration struct {
+	Default  string                            `yaml:"default"`
+	Profiles map[string]map[string]interface{} `yaml:"profiles"`
+}
+
+// statusesKey is the profile key of the inline status mapping
+const statusesKey = "statuses"
+
+// pathKeys are the profile keys with file names, relative ones are relative to the config file
+var pathKeys = map[string]bool{"token-file": true, "cache-dir": true, "status-map": true, "record": true, "replay": true}
+
+// envKeys are the environment variables of the flags, they take precedence over the profile
+var envKeys = map[string][]string{
+	"auth":       {"JIRA_AUTH"},
+	"user":       {"JIRA_USER"},
+	"password":   {"JIRA_PASSWORD"},
+	"token-file": {"JIRA_TOKEN", "JIRA_PASSWORD"},
+}
+
+// profileStatuses is the status mapping of the selected profile, if any
+var profileStatuses *burndown.StatusMapping
+
+func defaultConfigFile() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "jira-report", "config.yaml")
+}
+
+// applyProfile sets the flags not given on the command line or the environment from the selected profile of the
+// config file
+func applyProfile(cmd *cobra.Command, args []string) error {
+	if configFile == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(configFile)
+	if os.IsNotExist(err) && profile == "" {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not read config file: %v", err)
+	}
+	var c configuration
+	err = yaml.UnmarshalStrict(b, &c)
+	if err != nil {
+		return fmt.Errorf("could not parse config file %s: %v", configFile, err)
+	}
+	name := profile
+	if name == "" {
+		name = c.Default
+	}
+	if name == "" {
+		return nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return fmt.Errorf("profile '%s' not found in %s", name, configFile)
+	}
+
+	flags := cmd.Flags()
+	given := make(map[string]bool)
+	flags.Visit(func(f *pflag.Flag) {
+		given[f.Name] = true
+	})
+	known := knownFlags(cmd.Root())
+	for key, v := range p {
+		if key == statusesKey {
+			if p["status-map"] != nil {
+				return fmt.Errorf("profile '%s' has both status-map and statuses, use one", name)
+			}
+			if given["status-map"] || given["board-columns"] {
+				continue
+			}
+			profileStatuses, err = parseStatuses(v)
+			if err != nil {
+				return fmt.Errorf("could not parse statuses of profile '%s': %v", name, err)
+			}
+			continue
+		}
+		if !known[key] {
+			return fmt.Errorf("unknown setting '%s' in profile '%s', expected a flag name", key, name)
+		}
+		if flags.Lookup(key) == nil || given[key] || inEnv(key) || v == nil {
+			//Not used by this command or overridden
+			continue
+		}
+		values := []interface{}{v}
+		if list, ok := v.([]interface{}); ok {
+			values = list
+		}
+		for _, v := range values {
+			s := fmt.Sprint(v)
+			if pathKeys[key] {
+				s = configPath(s)
+			}
+			err = flags.Set(key, s)
+			if err != nil {
+				return fmt.Errorf("invalid %s '%s' in profile '%s': %v", key, s, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// inEnv tells if an environment variable of the flag is set
+func inEnv(key string) bool {
+	for _, name := range envKeys[key] {
+		if os.Getenv(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// knownFlags gives the names of the flags of the command and its subcommands
+func knownFlags(cmd *cobra.Command) map[string]bool {
+	known := make(map[string]bool)
+	add := func(f *pflag.Flag) {
+		known[f.Name] = true
+	}
+	cmd.Flags().VisitAll(add)
+	cmd.PersistentFlags().VisitAll(add)
+	for _, sub := range cmd.Commands() {
+		for name := range knownFlags(sub) {
+			known[name] = true
+		}
+	}
+	return known
+}
+
+func parseStatuses(v interface{}) (*burndown.StatusMapping, error) {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m burndown.StatusMapping
+	err = yaml.UnmarshalStrict(b, &m)
+	return &m, err
+}
+
+// configPath expands ~ and makes relative paths relative to the directory of the config file
+func configPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(configFile), path)
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+const testConfig = `default: work
+profiles:
+  work:
+    auth: bearer
+    token-file: token
+    board: Team board
+    format: [html, json]
+    workday-start: 9
+  cloud:
+    auth: api-token
+    user: me@example.com
+  server:
+    board: Server board
+`
+
+// settings are the flags of the test commands
+type settings struct {
+	auth, user, tokenFile, board string
+	formats                      []string
+	workdayStart                 int
+}
+
+// profileCommand gives a command with a root like the reports, the auth default taken from $JIRA_AUTH
+func profileCommand(s *settings) *cobra.Command {
+	root := &cobra.Command{Use: "reports"}
+	root.PersistentFlags().StringVar(&s.auth, "auth", envOr("JIRA_AUTH", "basic"), "")
+	root.PersistentFlags().StringVar(&s.user, "user", "", "")
+	root.PersistentFlags().StringVar(&s.tokenFile, "token-file", "", "")
+	sub := &cobra.Command{Use: "burndown"}
+	sub.Flags().StringVar(&s.board, "board", "", "")
+	sub.Flags().StringArrayVar(&s.formats, "format", []string{"html"}, "")
+	sub.Flags().IntVar(&s.workdayStart, "workday-start", 8, "")
+	root.AddCommand(sub)
+	return sub
+}
+
+func TestApplyProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "config.yaml")
+	err = ioutil.WriteFile(file, []byte(testConfig), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func(c, p, e string) {
+		configFile, profile = c, p
+		os.Setenv("JIRA_AUTH", e)
+	}(configFile, profile, os.Getenv("JIRA_AUTH"))
+	configFile = file
+
+	tests := []struct {
+		name    string
+		profile string
+		env     string
+		args    []string
+		want    settings
+		err     string
+	}{
+		{name: "default profile", want: settings{"bearer", "", filepath.Join(dir, "token"), "Team board", []string{"html", "json"}, 9}},
+		{name: "env over profile", env: "cookie", want: settings{"cookie", "", filepath.Join(dir, "token"), "Team board", []string{"html", "json"}, 9}},
+		{
+			name: "flags over profile",
+			args: []string{"--auth", "basic", "--board", "Other board", "--format", "csv", "--workday-start", "10"},
+			want: settings{"basic", "", filepath.Join(dir, "token"), "Other board", []string{"csv"}, 10},
+		},
+		{name: "selected profile", profile: "cloud", want: settings{"api-token", "me@example.com", "", "", []string{"html"}, 8}},
+		{name: "env without profile setting", profile: "server", env: "cookie", want: settings{"cookie", "", "", "Server board", []string{"html"}, 8}},
+		{name: "unknown profile", profile: "home", err: "profile 'home' not found in " + file},
+	}
+	for _, tt := range tests {
+		os.Setenv("JIRA_AUTH", tt.env)
+		profile = tt.profile
+		var got settings
+		cmd := profileCommand(&got)
+		err := cmd.ParseFlags(tt.args)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		err = applyProfile(cmd, nil)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: err = %v, want %s", tt.name, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: settings = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
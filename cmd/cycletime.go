@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"reports/burndown"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	cycleTimeOutput string
+	window          int
+)
+
+func init() {
+	addSprintFlag(cycleTimeCmd)
+	addBoardFlags(cycleTimeCmd, "Name or ID of the board to use. With --jql only its working days and columns are used.")
+	addTimeRangeFlags(cycleTimeCmd)
+	cycleTimeCmd.Flags().IntVar(&window, "window", 5, "Number of issues of the rolling average of the cycle time.")
+	addOutputFlag(cycleTimeCmd, &cycleTimeOutput, "cycletime.html")
+	addWorkTimeFlags(cycleTimeCmd, "Give the times in calendar days instead of work hours.")
+	addFormatFlags(cycleTimeCmd, true)
+	addStatusMapFlag(cycleTimeCmd, "buckets, the first bucket is waiting and the later ones in progress")
+	cycleTimeCmd.Flags().BoolVar(&boardColumns, "board-columns", boardColumns, "Use the board columns, the first column is waiting and the later ones in progress. Cannot be used with --status-map.")
+	rootCmd.AddCommand(cycleTimeCmd)
+}
+
+var cycleTimeCmd = &cobra.Command{
+	Use:          "cycletime",
+	Short:        "Generate the lead and cycle time control chart of the issues completed in a sprint or a time range.",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := commandContext()
+		defer cancel()
+		mapping, err := statusMapping()
+		if err != nil {
+			return err
+		}
+		opts := burndown.Opts{
+			Client:       c,
+			Board:        board,
+			BoardFilter:  boardFilter(),
+			Interactive:  interactive,
+			FullTimeline: fullTimeline,
+			WorkdayStart: workdayStart,
+			WorkdayEnd:   workdayEnd,
+			Renderer:     burndown.Renderer(renderer),
+			Statuses:     mapping,
+			BoardColumns: boardColumns,
+		}
+		return forEachScope(cycleTimeOutput, opts, func(opts burndown.Opts, outfile string) error {
+			r, err := burndown.GenerateCycleTime(ctx, opts, window)
+			if err != nil {
+				return err
+			}
+			return logFiles(r.WriteFiles(outfile, outputFormats()...))
+		})
+	},
+}
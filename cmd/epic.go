@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"reports/burndown"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	epicOutput string
+	epics      []string
+)
+
+func init() {
+	epicCmd.Flags().StringArrayVarP(&epics, "epic", "e", nil, "Key of the epic to get the report for. Can be repeated, all epics are reported in one file.")
+	addBoardFlags(epicCmd, workWeekBoardUsage)
+	addOutputFlag(epicCmd, &epicOutput, "epics.html")
+	addWorkTimeFlags(epicCmd, fullTimelineUsage)
+	addFormatFlags(epicCmd, true)
+	addStatusMapFlag(epicCmd, "the stacked chart buckets and the done statuses")
+	epicCmd.Flags().BoolVar(&boardColumns, "board-columns", boardColumns, "Stack the board columns in the chart, the last column being done. Cannot be used with --status-map.")
+	addMetricFlag(epicCmd, &metric, burndown.RemainingTime, "burn down")
+	rootCmd.AddCommand(epicCmd)
+}
+
+var epicCmd = &cobra.Command{
+	Use:          "epic",
+	Short:        "Generate the burndown of epics with their child issues and sub-tasks, and a portfolio summary.",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := commandContext()
+		defer cancel()
+		mapping, err := statusMapping()
+		if err != nil {
+			return err
+		}
+		opts := burndown.Opts{
+			Client:       c,
+			Board:        board,
+			BoardFilter:  boardFilter(),
+			Interactive:  interactive,
+			FullTimeline: fullTimeline,
+			WorkdayStart: workdayStart,
+			WorkdayEnd:   workdayEnd,
+			Metric:       burndown.Metric(metric),
+			Renderer:     burndown.Renderer(renderer),
+			Statuses:     mapping,
+			BoardColumns: boardColumns,
+		}
+		r, err := burndown.GenerateEpics(ctx, opts, epics)
+		if err != nil {
+			return err
+		}
+		return logFiles(r.WriteFiles(epicOutput, outputFormats()...))
+	},
+}
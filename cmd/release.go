@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"errors"
+	"reports/burndown"
+
+	"github.com/spf13/cobra"
+)
+
+var releaseOutput, fixVersion string
+
+func init() {
+	releaseCmd.Flags().StringVar(&fixVersion, "fix-version", "", "Name or ID of the fix version to get the report for. Default gets the only unreleased version of the project.")
+	addBoardFlags(releaseCmd, workWeekBoardUsage)
+	releaseCmd.Flags().Lookup("project").Usage = "Key or ID of the project of the fix version, also narrows the board lookup."
+	addOutputFlag(releaseCmd, &releaseOutput, "release.html")
+	releaseCmd.Flags().BoolVar(&startMargin, "start-margin", startMargin, "add additional 1 day margin before the version start")
+	addWorkTimeFlags(releaseCmd, fullTimelineUsage)
+	releaseCmd.Flags().Lookup("workday-end").Usage = "When does the working day end (0-23), also the time of the release on the release date."
+	addFormatFlags(releaseCmd, true)
+	addStatusMapFlag(releaseCmd, "the stacked chart buckets and the done statuses")
+	releaseCmd.Flags().BoolVar(&boardColumns, "board-columns", boardColumns, "Stack the board columns in the chart, the last column being done. Cannot be used with --status-map.")
+	addMetricFlag(releaseCmd, &metric, burndown.RemainingTime, "burn down")
+	rootCmd.AddCommand(releaseCmd)
+}
+
+var releaseCmd = &cobra.Command{
+	Use:          "release",
+	Short:        "Generate the burndown of a fix version over its lifetime, up to the release date.",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if project == "" {
+			return errors.New("--project is required to find the fix version")
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := commandContext()
+		defer cancel()
+		mapping, err := statusMapping()
+		if err != nil {
+			return err
+		}
+		opts := burndown.Opts{
+			Client:       c,
+			Board:        board,
+			BoardFilter:  boardFilter(),
+			Interactive:  interactive,
+			StartMargin:  startMargin,
+			FullTimeline: fullTimeline,
+			WorkdayStart: workdayStart,
+			WorkdayEnd:   workdayEnd,
+			Metric:       burndown.Metric(metric),
+			Renderer:     burndown.Renderer(renderer),
+			Statuses:     mapping,
+			BoardColumns: boardColumns,
+		}
+		r, err := burndown.GenerateRelease(ctx, opts, project, fixVersion)
+		if err != nil {
+			return err
+		}
+		return logFiles(r.WriteFiles(releaseOutput, outputFormats()...))
+	},
+}
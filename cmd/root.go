@@ -1,21 +1,39 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"reports/jira"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "reports",
-	Short: "JIRA report generator",
+	Use:               "reports",
+	Short:             "JIRA report generator",
+	PersistentPreRunE: applyProfile,
 }
 
 var (
 	user, url   string
 	password    string
+	authMode    = envOr("JIRA_AUTH", string(jira.BasicAuth))
+	tokenFile   string
 	interactive bool
+	configFile  = envOr("JIRA_REPORT_CONFIG", defaultConfigFile())
+	profile     = os.Getenv("JIRA_PROFILE")
+	cacheDir    = defaultCacheDir()
+	noCache     bool
+	refresh     bool
+	offline     bool
+	record      string
+	replay      string
 )
 
 // Execute runs the root command
@@ -27,9 +45,137 @@ func Execute() {
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVarP(&user, "user", "u", "", "The user to log into JIRA as. Use 'user:pass' to skip interactive password prompt.")
+	rootCmd.PersistentFlags().StringVarP(&user, "user", "u", "", "The user to log into JIRA as, the e-mail address for API tokens. Use 'user:pass' to skip interactive password prompt.")
 	rootCmd.PersistentFlags().StringVar(&password, "password", "", "The password to use for JIRA user. Also see '--user'.")
+	rootCmd.PersistentFlags().StringVar(&authMode, "auth", authMode, fmt.Sprintf("Authentication mode, one of %v. Defaults to $JIRA_AUTH.", jira.AuthModes))
+	rootCmd.PersistentFlags().StringVar(&tokenFile, "token-file", "", "File with the password, API token, Personal Access Token or session cookie. Defaults to $JIRA_TOKEN, or $JIRA_PASSWORD for basic authentication.")
 	rootCmd.PersistentFlags().StringVar(&url, "url", url, "JIRA URL")
 	rootCmd.PersistentFlags().BoolVarP(&interactive, "interactive", "i", interactive, "Enable interactive prompts")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", configFile, "YAML config file with named profiles of settings. Defaults to $JIRA_REPORT_CONFIG.")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", profile, "Profile of the config file to use, flags and environment variables override its settings. Defaults to $JIRA_PROFILE or the default profile of the config file.")
+	rootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", cacheDir, "Directory to cache the issues and their changelogs in, per JIRA instance.")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", noCache, "Do not use the cache, download everything without storing it.")
+	rootCmd.PersistentFlags().BoolVar(&refresh, "refresh", refresh, "Download everything again, replacing the cached data. Default only downloads the issues updated since the last run.")
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", offline, "Do not contact JIRA, read everything from the cache of previous runs.")
+	rootCmd.PersistentFlags().StringVar(&record, "record", "", "Store sanitized copies of all JIRA responses in the directory, for replaying them with '--replay'. Disables the cache.")
+	rootCmd.PersistentFlags().StringVar(&replay, "replay", "", "Serve the JIRA responses recorded with '--record' from the directory, without contacting JIRA. Disables the cache.")
 	rootCmd.MarkPersistentFlagRequired("url")
 }
+
+func envOr(name, value string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return value
+}
+
+// credentials gives the authentication selected by the flags and environment variables.
+// Secrets are read from the token file or the environment, only the password may be given as a flag.
+func credentials() (jira.Auth, error) {
+	auth := jira.Auth{Mode: jira.AuthMode(authMode), User: user}
+	if auth.User == "" {
+		auth.User = os.Getenv("JIRA_USER")
+	}
+	if auth.Mode == jira.APIToken && auth.User == "" {
+		return auth, fmt.Errorf("--auth %s needs the e-mail address of the JIRA Cloud account in --user or $JIRA_USER", jira.APIToken)
+	}
+	if password != "" {
+		if auth.Mode != jira.BasicAuth {
+			return auth, fmt.Errorf("--password is only used for basic authentication, give the %s in --token-file or $JIRA_TOKEN", auth.Mode)
+		}
+		auth.Secret = password
+		return auth, nil
+	}
+	if tokenFile != "" {
+		b, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return auth, fmt.Errorf("could not read token file: %v", err)
+		}
+		auth.Secret = strings.TrimSpace(string(b))
+		return auth, nil
+	}
+	if auth.Mode == jira.BasicAuth {
+		auth.Secret = os.Getenv("JIRA_PASSWORD")
+	} else {
+		auth.Secret = os.Getenv("JIRA_TOKEN")
+	}
+	return auth, nil
+}
+
+func defaultCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "jira-report")
+}
+
+// newClient creates the JIRA client with the cache selected by the flags
+func newClient() (*jira.Client, error) {
+	//The incremental searches of the cache depend on the time of the last run, so they can't be replayed
+	switch {
+	case record != "" && replay != "":
+		return nil, errors.New("--record and --replay can't be used together")
+	case record != "" && offline:
+		return nil, errors.New("--record and --offline can't be used together")
+	case replay != "":
+		return jira.InitReplay(replay, url)
+	case offline && (noCache || cacheDir == ""):
+		return nil, errors.New("offline mode needs the cache, set --cache-dir and don't use --no-cache")
+	}
+	if offline && refresh {
+		return nil, errors.New("--offline and --refresh can't be used together")
+	}
+	var auth jira.Auth
+	if !offline {
+		var err error
+		auth, err = credentials()
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case record != "":
+		rec, err := jira.Record(record, url)
+		if err != nil {
+			return nil, err
+		}
+		return jira.InitJiraWithAuth(auth, url, rec)
+	case noCache || cacheDir == "":
+		return jira.InitJiraWithAuth(auth, url)
+	}
+	var c *jira.Client
+	var err error
+	mode := jira.CacheUpdate
+	switch {
+	case offline:
+		mode = jira.CacheOffline
+		c, err = jira.InitOffline(url)
+	case refresh:
+		mode = jira.CacheRefresh
+		fallthrough
+	default:
+		c, err = jira.InitJiraWithAuth(auth, url)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.UseCache(jira.NewCache(cacheDir, url, mode))
+	return c, nil
+}
+
+// commandContext gives a context that is cancelled on interrupt
+func commandContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		select {
+		case <-sig:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(sig)
+	}()
+	return ctx, cancel
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var sprintStates []string
+
+func init() {
+	addBoardFlags(sprintsCmd, "Name or ID of the Sprint board to list the sprints of.")
+	sprintsCmd.Flags().StringSliceVar(&sprintStates, "state", nil, "States of the sprints to list: 'active', 'closed' and/or 'future', comma separated. Default lists all.")
+	sprintsCmd.Flags().BoolVar(&listJSON, "json", listJSON, "Print JSON instead of a table.")
+	rootCmd.AddCommand(sprintsCmd)
+}
+
+var sprintsCmd = &cobra.Command{
+	Use:          "sprints",
+	Short:        "List the sprints of a board with their IDs, states and dates.",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := commandContext()
+		defer cancel()
+		boardID, err := c.GetScrumBoardID(ctx, board, boardFilter(), interactive)
+		if err != nil {
+			return err
+		}
+		sprints, err := c.GetSprints(ctx, boardID, sprintStates...)
+		if err != nil {
+			return err
+		}
+		var rows [][]string
+		for _, s := range sprints {
+			rows = append(rows, []string{fmt.Sprint(s.ID), s.Name, s.State, formatDate(s.StartDate), formatDate(s.EndDate), formatDate(s.CompleteDate), fmt.Sprint(s.OriginBoardID)})
+		}
+		return printList(cmd.OutOrStdout(), sprints, []string{"ID", "NAME", "STATE", "START", "END", "COMPLETE", "BOARD"}, rows)
+	},
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
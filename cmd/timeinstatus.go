@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"reports/burndown"
+
+	"github.com/spf13/cobra"
+)
+
+var timeInStatusOutput string
+
+func init() {
+	addSprintFlag(timeInStatusCmd)
+	addBoardFlags(timeInStatusCmd, "Name or ID of the board to use. With --jql only its working days are used.")
+	addTimeRangeFlags(timeInStatusCmd)
+	addOutputFlag(timeInStatusCmd, &timeInStatusOutput, "time-in-status.html")
+	addWorkTimeFlags(timeInStatusCmd, "")
+	addFormatFlags(timeInStatusCmd, false)
+	rootCmd.AddCommand(timeInStatusCmd)
+}
+
+var timeInStatusCmd = &cobra.Command{
+	Use:          "time-in-status",
+	Short:        "Generate the time the issues of a sprint or a time range spent in each status.",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := commandContext()
+		defer cancel()
+		opts := burndown.Opts{
+			Client:       c,
+			Board:        board,
+			BoardFilter:  boardFilter(),
+			Interactive:  interactive,
+			WorkdayStart: workdayStart,
+			WorkdayEnd:   workdayEnd,
+		}
+		return forEachScope(timeInStatusOutput, opts, func(opts burndown.Opts, outfile string) error {
+			r, err := burndown.GenerateTimeInStatus(ctx, opts)
+			if err != nil {
+				return err
+			}
+			return logFiles(r.WriteFiles(outfile, outputFormats()...))
+		})
+	},
+}
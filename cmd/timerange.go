@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"reports/burndown"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var jql, from, to, last string
+
+// addTimeRangeFlags adds the flags reporting a time range instead of a sprint
+func addTimeRangeFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&jql, "jql", "", "JQL selecting the issues instead of a sprint. Requires --from or --last.")
+	cmd.Flags().StringVar(&from, "from", "", "Start of the report instead of a sprint, as YYYY-MM-DD or 'YYYY-MM-DD hh:mm' in local time. Without --jql the issues of the board are reported, e.g. of a kanban board.")
+	cmd.Flags().StringVar(&to, "to", "", "End of the report with --from, as YYYY-MM-DD or 'YYYY-MM-DD hh:mm' in local time. Default is now.")
+	cmd.Flags().StringVar(&last, "last", "", "Report the time up to now instead of a sprint, e.g. 14d, 2w or 36h. Cannot be used with --from.")
+}
+
+// forEachScope runs the report for the time range of --from or --last, or else for each given sprint like forEachSprint
+func forEachScope(outfile string, opts burndown.Opts, run func(opts burndown.Opts, outfile string) error) error {
+	if jql != "" || from != "" || to != "" || last != "" {
+		var err error
+		opts.JQL = jql
+		opts.From, opts.To, err = timeRange()
+		if err != nil {
+			return err
+		}
+		return run(opts, outfile)
+	}
+	return forEachSprint(outfile, func(sprint, outfile string) error {
+		opts.Sprint = sprint
+		return run(opts, outfile)
+	})
+}
+
+// timeRange gives the time range of --from and --to, or of --last up to now. The end defaults to now.
+func timeRange() (time.Time, time.Time, error) {
+	end := time.Now()
+	if last != "" {
+		if from != "" || to != "" {
+			return time.Time{}, time.Time{}, fmt.Errorf("--last cannot be used with --from or --to")
+		}
+		d, err := parseLast(last)
+		return end.Add(-d), end, err
+	}
+	if from == "" && to != "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("--to requires --from")
+	}
+	if from == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("--from or --last is required with --jql")
+	}
+	start, err := parseTime("from", from)
+	if err != nil {
+		return start, start, err
+	}
+	if to != "" {
+		end, err = parseTime("to", to)
+	}
+	return start, end, err
+}
+
+func parseTime(flag, value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --%s '%s', expected YYYY-MM-DD or 'YYYY-MM-DD hh:mm'", flag, value)
+}
+
+// parseLast parses the duration of --last in days (14d), weeks (2w) or as Go duration (36h)
+func parseLast(value string) (time.Duration, error) {
+	days := map[string]int{"d": 1, "w": 7}
+	for suffix, n := range days {
+		if v, err := strconv.Atoi(strings.TrimSuffix(value, suffix)); err == nil && strings.HasSuffix(value, suffix) && v > 0 {
+			return time.Duration(v*n) * 24 * time.Hour, nil
+		}
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid --last '%s', expected e.g. 14d, 2w or 36h", value)
+	}
+	return d, nil
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"reports/burndown"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	velocityOutput  string
+	velocitySprints = 6
+)
+
+func init() {
+	addBoardFlags(velocityCmd, "Name or ID of the Sprint board to use.")
+	velocityCmd.Flags().IntVar(&velocitySprints, "sprints", velocitySprints, "Number of the last closed sprints to include.")
+	addOutputFlag(velocityCmd, &velocityOutput, "velocity.html")
+	addFormatFlags(velocityCmd, true)
+	addStatusMapFlag(velocityCmd, "buckets, the done statuses count as completed")
+	velocityCmd.Flags().BoolVar(&boardColumns, "board-columns", boardColumns, "Count the last board column as done. Cannot be used with --status-map.")
+	addMetricFlag(velocityCmd, &metric, burndown.RemainingTime, "sum up")
+	rootCmd.AddCommand(velocityCmd)
+}
+
+var velocityCmd = &cobra.Command{
+	Use:          "velocity",
+	Short:        "Generate the velocity report of committed and completed effort in the last closed sprints of a board.",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := commandContext()
+		defer cancel()
+		mapping, err := statusMapping()
+		if err != nil {
+			return err
+		}
+		opts := burndown.Opts{
+			Client:       c,
+			Board:        board,
+			BoardFilter:  boardFilter(),
+			Interactive:  interactive,
+			WorkdayStart: workdayStart,
+			WorkdayEnd:   workdayEnd,
+			Metric:       burndown.Metric(metric),
+			Renderer:     burndown.Renderer(renderer),
+			Statuses:     mapping,
+			BoardColumns: boardColumns,
+		}
+		v, err := burndown.GenerateVelocity(ctx, opts, velocitySprints)
+		if err != nil {
+			return err
+		}
+		return logFiles(v.WriteFiles(velocityOutput, outputFormats()...))
+	},
+}
@@ -2,8 +2,10 @@ package jira
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -11,42 +13,54 @@ import (
 	jira "gopkg.in/andygrunwald/go-jira.v1"
 )
 
+// BoardFilter narrows the boards to choose from
+type BoardFilter struct {
+	// Project is the key or ID of the project the boards belong to
+	Project string
+	// Type is the board type: scrum, kanban or simple
+	Type string
+}
+
 // GetScrumBoardID gets the jira ID of the given board name or if empty, interactively lets you choose
-func (c *Client) GetScrumBoardID(board string, interact bool) (int, error) {
+func (c *Client) GetScrumBoardID(ctx context.Context, board string, filter BoardFilter, interact bool) (int, error) {
 	r, err := strconv.Atoi(board)
 	if err == nil {
 		//If board is a number, we already found it
 		return r, nil
 	}
-	//Else try to resolve by name
-	v, err := c.getBoards()
+	//Else try to resolve by name, JIRA matches names partially
+	boards, err := c.getBoards(ctx, filter, board)
 	if err != nil {
 		return 0, err
 	}
 	if board != "" {
-		for _, b := range v.Values {
+		var matches []jira.Board
+		for _, b := range boards {
 			if b.Name == board {
-				return b.ID, nil
+				matches = append(matches, b)
 			}
 		}
-		return 0, errors.New("could not find a matching board for '" + board + "'")
+		switch {
+		case len(matches) == 0:
+			return 0, &NotFoundError{Kind: "board", Name: board}
+		case len(matches) == 1:
+			return matches[0].ID, nil
+		case !interact:
+			return 0, &AmbiguousError{Kind: "board", Name: board, Options: boardOptions(matches)}
+		}
+		boards = matches
 	}
-	if len(v.Values) == 0 {
+	if len(boards) == 0 {
 		return 0, errors.New("no boards found")
 	}
-	if len(v.Values) == 1 {
-		return v.Values[0].ID, nil
+	if len(boards) == 1 {
+		return boards[0].ID, nil
 	}
 	if !interact {
-		var opts []string
-		for _, b := range v.Values {
-			opts = append(opts, fmt.Sprintf("%s (%v)", b.Name, b.ID))
-		}
-		return 0, errors.New("board must be selected, options are:" + strings.Join(opts, ","))
+		return 0, &NotFoundError{Kind: "board", Options: boardOptions(boards)}
 	}
-	b, err := runInteractiveLoop(makeBoardOptions(v.Values), func(v interface{}) string {
-		s := v.(jira.Board)
-		return fmt.Sprintf("%s (%v)", s.Name, s.ID)
+	b, err := runInteractiveLoop(makeBoardOptions(boards), func(v interface{}) string {
+		return boardOption(v.(jira.Board))
 	})
 	if err != nil {
 		return 0, err
@@ -54,6 +68,18 @@ func (c *Client) GetScrumBoardID(board string, interact bool) (int, error) {
 	return b.(jira.Board).ID, nil
 }
 
+func boardOption(b jira.Board) string {
+	return fmt.Sprintf("%s (%v, %s)", b.Name, b.ID, b.Type)
+}
+
+func boardOptions(boards []jira.Board) []string {
+	var opts []string
+	for _, b := range boards {
+		opts = append(opts, boardOption(b))
+	}
+	return opts
+}
+
 type options struct {
 	items []interface{}
 }
@@ -103,13 +129,13 @@ func GetNumber(id string) (int, bool) {
 }
 
 // FindSprint retrieves the sprint data based on the given name or ID of the sprint
-func (c *Client) FindSprint(board int, sprintName string, interactive bool) (jira.Sprint, error) {
+func (c *Client) FindSprint(ctx context.Context, board int, sprintName string, interactive bool) (jira.Sprint, error) {
 	id, ok := GetNumber(sprintName)
 	if ok {
-		return c.GetSprint(id)
+		return c.GetSprint(ctx, id)
 	}
 	// Else try match the name on the selected board
-	sprints, _, err := c.Board.GetAllSprints(fmt.Sprint(board))
+	sprints, err := c.GetSprints(ctx, board)
 	if err != nil {
 		return jira.Sprint{}, err
 	}
@@ -133,42 +159,89 @@ func (c *Client) FindSprint(board int, sprintName string, interactive bool) (jir
 		}
 		return result.(jira.Sprint), nil
 	}
-	return jira.Sprint{}, fmt.Errorf("no sprint '%s' found", sprintName)
+	return jira.Sprint{}, &NotFoundError{Kind: "sprint", Name: sprintName}
 }
 
 // GetSprint gets the sprint with given ID
-func (c *Client) GetSprint(sprintID int) (jira.Sprint, error) {
+func (c *Client) GetSprint(ctx context.Context, sprintID int) (jira.Sprint, error) {
 	apiEndpoint := fmt.Sprintf("rest/agile/1.0/sprint/%d", sprintID)
 
-	req, err := c.NewRequest("GET", apiEndpoint, nil)
-
-	if err != nil {
-		return jira.Sprint{}, err
-	}
-
 	var result jira.Sprint
-	resp, err := c.Do(req, &result)
-	if err != nil {
-		err = jira.NewJiraError(resp, err)
-	}
+	err := c.get(ctx, apiEndpoint, &result)
+	return result, err
+}
+
+// GetBoard gets the board with given ID
+func (c *Client) GetBoard(ctx context.Context, boardID int) (jira.Board, error) {
+	apiEndpoint := fmt.Sprintf("rest/agile/1.0/board/%d", boardID)
 
+	var result jira.Board
+	err := c.get(ctx, apiEndpoint, &result)
 	return result, err
 }
 
-func (c *Client) getBoards() (*jira.BoardsList, error) {
-	bs, _, err := c.Board.GetAllBoards(nil)
-	return bs, err
+// GetBoards gets the boards visible to the user
+func (c *Client) GetBoards(ctx context.Context, filter BoardFilter) ([]jira.Board, error) {
+	return c.getBoards(ctx, filter, "")
+}
+
+// GetSprints gets the sprints of the board in the given states: active, closed or future. Without states all sprints are returned.
+func (c *Client) GetSprints(ctx context.Context, board int, states ...string) ([]jira.Sprint, error) {
+	query := url.Values{}
+	if len(states) > 0 {
+		query.Set("state", strings.Join(states, ","))
+	}
+	var sprints []jira.Sprint
+	for {
+		query.Set("startAt", strconv.Itoa(len(sprints)))
+		var result jira.SprintsList
+		err := c.get(ctx, fmt.Sprintf("rest/agile/1.0/board/%d/sprint?%s", board, query.Encode()), &result)
+		if err != nil {
+			return nil, err
+		}
+		sprints = append(sprints, result.Values...)
+		if result.IsLast || len(result.Values) == 0 {
+			return sprints, nil
+		}
+	}
+}
+
+// getBoards gets all pages of the boards matching the filter and, partially, the name if given
+func (c *Client) getBoards(ctx context.Context, filter BoardFilter, name string) ([]jira.Board, error) {
+	query := url.Values{}
+	if filter.Project != "" {
+		query.Set("projectKeyOrId", filter.Project)
+	}
+	if filter.Type != "" {
+		query.Set("type", filter.Type)
+	}
+	if name != "" {
+		query.Set("name", name)
+	}
+	var boards []jira.Board
+	for {
+		query.Set("startAt", strconv.Itoa(len(boards)))
+		var result jira.BoardsList
+		err := c.get(ctx, "rest/agile/1.0/board?"+query.Encode(), &result)
+		if err != nil {
+			return nil, err
+		}
+		boards = append(boards, result.Values...)
+		if result.IsLast || len(result.Values) == 0 {
+			return boards, nil
+		}
+	}
 }
 
 // GetActiveSprint gets an active sprint
-func (c *Client) GetActiveSprint(board int) (jira.Sprint, error) {
-	s, _, err := c.Board.GetAllSprintsWithOptions(board, &jira.GetAllSprintsOptions{State: "active"})
+func (c *Client) GetActiveSprint(ctx context.Context, board int) (jira.Sprint, error) {
+	s, err := c.GetSprints(ctx, board, "active")
 	if err != nil {
 		return jira.Sprint{}, err
 	}
 	//assume we have at least one active sprint, use the first entry
-	if len(s.Values) == 0 {
-		return jira.Sprint{}, errors.New("there are no active sprints")
+	if len(s) == 0 {
+		return jira.Sprint{}, ErrNoActiveSprint
 	}
-	return s.Values[0], err
+	return s[0], err
 }
@@ -3,7 +3,7 @@ package jira
 import (
 	"bufio"
 	"fmt"
-	"log"
+	"net/http"
 	"os"
 	"strings"
 	"syscall"
@@ -15,27 +15,135 @@ import (
 // Client is a local wrapper for remote JIRA client
 type Client struct {
 	*jira.Client
+	cache *Cache
+}
+
+// AuthMode selects how the client authenticates to JIRA
+type AuthMode string
+
+const (
+	// BasicAuth uses the user name and password
+	BasicAuth AuthMode = "basic"
+	// APIToken uses the e-mail address and an API token, as required by JIRA Cloud
+	APIToken AuthMode = "api-token"
+	// BearerToken uses a Personal Access Token of JIRA Server or Data Center
+	BearerToken AuthMode = "bearer"
+	// SessionCookie uses the session cookie of a logged in browser, e.g. for single sign-on
+	SessionCookie AuthMode = "cookie"
+)
+
+// AuthModes lists the supported authentication modes
+var AuthModes = []AuthMode{BasicAuth, APIToken, BearerToken, SessionCookie}
+
+// Auth holds the credentials for JIRA
+type Auth struct {
+	Mode AuthMode
+	// User is the user name, or the e-mail address required for API tokens. Not used by the other modes.
+	User string
+	// Secret is the password, API token, Personal Access Token or session cookie.
+	// A cookie without a name is sent as JSESSIONID.
+	Secret string
 }
 
 // InitJira creates the JIRA client instance authenticating with the provided credentials
-// or requests password from `stdin` if not provided
-func InitJira(user, pass, url string) *Client {
-	if pass == "" {
-		user, pass = getAuth(user)
+// or requests password from `stdin` if not provided. The options wrap the HTTP transport, e.g. Record.
+func InitJira(user, pass, url string, options ...Option) (*Client, error) {
+	return InitJiraWithAuth(Auth{Mode: BasicAuth, User: user, Secret: pass}, url, options...)
+}
+
+// InitJiraWithAuth creates the JIRA client instance authenticating with the given mode.
+// Missing credentials are requested from `stdin`.
+func InitJiraWithAuth(auth Auth, url string, options ...Option) (*Client, error) {
+	transport, err := auth.transport()
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range options {
+		transport = o(transport)
 	}
-	auth := jira.BasicAuthTransport{Username: user, Password: pass}
 	jiraClient, err := jira.NewClient(
-		auth.Client(),
+		&http.Client{Transport: transport},
 		url,
 	)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
+	}
+	return &Client{Client: jiraClient}, nil
+}
+
+// InitOffline creates the JIRA client instance for reading from the cache only, without credentials
+func InitOffline(url string) (*Client, error) {
+	jiraClient, err := jira.NewClient(nil, url)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{Client: jiraClient}, nil
+}
+
+func (a Auth) transport() (http.RoundTripper, error) {
+	var err error
+	switch a.Mode {
+	case BasicAuth, "":
+		if a.Secret == "" {
+			a.User, a.Secret, err = getAuth(a.User)
+		}
+		return &jira.BasicAuthTransport{Username: a.User, Password: a.Secret}, err
+	case APIToken:
+		//JIRA Cloud only accepts the token with the e-mail address, it is not prompted as it is no secret
+		if !strings.ContainsRune(a.User, '@') {
+			return nil, fmt.Errorf("the %s mode needs the e-mail address of the JIRA Cloud account as user, got '%s'", APIToken, a.User)
+		}
+		if a.Secret == "" {
+			a.Secret, err = readSecret(fmt.Sprintf("Please provide the JIRA API token for '%s': ", a.User))
+		}
+		return &jira.BasicAuthTransport{Username: a.User, Password: a.Secret}, err
+	case BearerToken:
+		if a.Secret == "" {
+			a.Secret, err = readSecret("Please provide the JIRA Personal Access Token: ")
+		}
+		return &headerTransport{"Authorization", "Bearer " + a.Secret}, err
+	case SessionCookie:
+		if a.Secret == "" {
+			a.Secret, err = readSecret("Please provide the JIRA session cookie: ")
+		}
+		cookie := a.Secret
+		if !strings.ContainsRune(cookie, '=') {
+			cookie = "JSESSIONID=" + cookie
+		}
+		return &headerTransport{"Cookie", cookie}, err
+	}
+	return nil, fmt.Errorf("unknown authentication mode '%s', expected one of: %v", a.Mode, AuthModes)
+}
+
+// headerTransport authenticates the requests with a header
+type headerTransport struct {
+	header, value string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	//RoundTrippers must not modify the request
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	r.Header.Set(t.header, t.value)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func readSecret(prompt string) (string, error) {
+	fmt.Print(prompt)
+	b, err := terminal.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("could not read secret: %v", err)
 	}
-	return &Client{jiraClient}
+	return string(b), nil
 }
 
 // getAuth splits user string into user and password based on first ':' or asks for password
-func getAuth(user string) (string, string) {
+func getAuth(user string) (string, string, error) {
 	var pass string
 	if strings.ContainsRune(user, ':') {
 		tokens := strings.SplitN(user, ":", 2)
@@ -48,7 +156,7 @@ func getAuth(user string) (string, string) {
 			var err error
 			user, err = reader.ReadString('\n')
 			if err != nil {
-				log.Fatal(err)
+				return "", "", fmt.Errorf("could not read user: %v", err)
 			}
 			user = strings.TrimSpace(user)
 		}
@@ -56,11 +164,10 @@ func getAuth(user string) (string, string) {
 		passBytes, err := terminal.ReadPassword(int(syscall.Stdin))
 		fmt.Println()
 		if err != nil {
-			log.Fatal(err)
+			return "", "", fmt.Errorf("could not read password: %v", err)
 		}
 
-		log.Println("Password read.")
 		pass = string(passBytes)
 	}
-	return user, pass
+	return user, pass, nil
 }
@@ -0,0 +1,241 @@
+package jira
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// CacheMode selects how the cache is used
+type CacheMode int
+
+const (
+	// CacheUpdate downloads the issues updated since the last sync and reads the others from the cache
+	CacheUpdate CacheMode = iota
+	// CacheRefresh downloads everything again, replacing the cached data
+	CacheRefresh
+	// CacheOffline reads everything from the cache without contacting JIRA
+	CacheOffline
+)
+
+// syncMargin is subtracted from the last sync time of a search,
+// so differences between the local time zone and the one of the JIRA user don't lose updates
+const syncMargin = 24 * time.Hour
+
+// Cache stores the issues with their changelogs, keyed by issue key, and the other responses
+// of one JIRA instance on disk.
+// Searches are refreshed incrementally: only the issues updated since the last search are downloaded again.
+type Cache struct {
+	dir  string
+	mode CacheMode
+}
+
+// NewCache gives the cache for the JIRA instance at instanceURL, stored in a directory under dir
+func NewCache(dir, instanceURL string, mode CacheMode) *Cache {
+	return &Cache{filepath.Join(dir, instanceDir(instanceURL)), mode}
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// instanceDir gives the directory name for the JIRA instance, e.g. jira.example.com_jira for https://jira.example.com/jira/
+func instanceDir(instanceURL string) string {
+	u := instanceURL
+	if i := strings.Index(u, "://"); i >= 0 {
+		u = u[i+3:]
+	}
+	return strings.Trim(unsafeChars.ReplaceAllString(u, "_"), "_")
+}
+
+// UseCache makes the client read from and write to the cache
+func (c *Client) UseCache(cache *Cache) {
+	c.cache = cache
+}
+
+// NotCachedError is returned in offline mode when the requested data is not in the cache
+type NotCachedError struct {
+	What string
+}
+
+func (e *NotCachedError) Error() string {
+	return fmt.Sprintf("%s is not in the cache, run once without offline mode first", e.What)
+}
+
+// cachedSearch records the issues matching a search at the time of the last sync
+type cachedSearch struct {
+	JQL      string    `json:"jql"`
+	LastSync time.Time `json:"lastSync"`
+	Keys     []string  `json:"keys"`
+}
+
+type cachedResponse struct {
+	Endpoint string          `json:"endpoint"`
+	Body     json.RawMessage `json:"body"`
+}
+
+func (cache *Cache) get(ctx context.Context, c *Client, apiEndpoint string, v interface{}) error {
+	file := filepath.Join(cache.dir, "requests", hash(apiEndpoint)+".json")
+	var r cachedResponse
+	if cache.mode == CacheOffline {
+		found, err := readJSON(file, &r)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return &NotCachedError{apiEndpoint}
+		}
+		return json.Unmarshal(r.Body, v)
+	}
+	err := c.fetch(ctx, apiEndpoint, &r.Body)
+	if err != nil {
+		return err
+	}
+	r.Endpoint = apiEndpoint
+	err = writeJSON(file, r)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(r.Body, v)
+}
+
+func (cache *Cache) search(ctx context.Context, c *Client, jql string, f func(jira.Issue) error) error {
+	file := filepath.Join(cache.dir, "searches", hash(jql)+".json")
+	var s cachedSearch
+	found, err := readJSON(file, &s)
+	if err != nil {
+		return err
+	}
+	if cache.mode == CacheOffline {
+		if !found {
+			return &NotCachedError{fmt.Sprintf("search '%s'", jql)}
+		}
+		return cache.replay(s.Keys, f)
+	}
+
+	sync := time.Now()
+	//The keys are cheap to get and tell which issues match now, including those that stopped matching
+	keys, err := c.searchKeys(ctx, jql)
+	if err != nil {
+		return err
+	}
+	updated := jql
+	if found && cache.mode == CacheUpdate {
+		updated = fmt.Sprintf(`(%s) AND updated >= "%s"`, jql, s.LastSync.Add(-syncMargin).Format("2006-01-02 15:04"))
+	}
+	fresh := make(map[string]bool)
+	store := func(key string, i fullIssue) error {
+		fresh[key] = true
+		return writeJSON(cache.issueFile(key), i)
+	}
+	err = c.searchWithChangelog(ctx, updated, store)
+	if err != nil {
+		return err
+	}
+	//Issues not updated since the last sync may still be missing, e.g. if the cache files were removed
+	//or a board filter changed to match them
+	var missing []string
+	for _, k := range keys {
+		if fresh[k] {
+			continue
+		}
+		if _, err := os.Stat(cache.issueFile(k)); os.IsNotExist(err) {
+			missing = append(missing, k)
+		}
+	}
+	for len(missing) > 0 {
+		n := len(missing)
+		if n > searchPageSize {
+			n = searchPageSize
+		}
+		err = c.searchWithChangelog(ctx, fmt.Sprintf("key in (%s)", strings.Join(missing[:n], ",")), store)
+		if err != nil {
+			return err
+		}
+		missing = missing[n:]
+	}
+
+	err = writeJSON(file, cachedSearch{jql, sync, keys})
+	if err != nil {
+		return err
+	}
+	return cache.replay(keys, f)
+}
+
+// replay calls f with the cached issues
+func (cache *Cache) replay(keys []string, f func(jira.Issue) error) error {
+	for _, k := range keys {
+		var i fullIssue
+		found, err := readJSON(cache.issueFile(k), &i)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return &NotCachedError{"issue " + k}
+		}
+		issue, err := i.decode()
+		if err != nil {
+			return fmt.Errorf("could not decode cached issue %s: %v", k, err)
+		}
+		err = f(issue)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cache *Cache) issueFile(key string) string {
+	return filepath.Join(cache.dir, "issues", key+".json")
+}
+
+func hash(s string) string {
+	h := sha1.Sum([]byte(s))
+	return hex.EncodeToString(h[:])
+}
+
+// readJSON decodes the file to v, found is false if the file does not exist
+func readJSON(file string, v interface{}) (found bool, err error) {
+	b, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	err = json.Unmarshal(b, v)
+	if err != nil {
+		return false, fmt.Errorf("could not read cache file %s: %v", file, err)
+	}
+	return true, nil
+}
+
+// writeJSON writes v to the file through a temporary file, so an interrupted run does not leave a broken file
+func writeJSON(file string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(file, b)
+}
+
+func writeFileAtomic(file string, b []byte) error {
+	err := os.MkdirAll(filepath.Dir(file), 0700)
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	err = ioutil.WriteFile(tmp, b, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
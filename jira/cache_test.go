@@ -0,0 +1,131 @@
+package jira_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"reflect"
+	agile "reports/jira"
+	"reports/jira/jiratest"
+	"strings"
+	"testing"
+	"time"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+const instance = "https://jira.example.com"
+
+// cachedStatuses searches the issues of sprint 10 through the cache and gives their statuses by key
+func cachedStatuses(t *testing.T, c *agile.Client) map[string]string {
+	statuses := make(map[string]string)
+	err := c.SearchWithChangelog(context.Background(), "sprint = 10", func(i jira.Issue) error {
+		statuses[i.Key] = i.Fields.Status.Name
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return statuses
+}
+
+func cachedClient(t *testing.T, srv *jiratest.Server, dir string, mode agile.CacheMode) *agile.Client {
+	c, err := agile.InitJira("user", "pass", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.UseCache(agile.NewCache(dir, instance, mode))
+	return c
+}
+
+// searches gives the JQL of the search requests received by the server
+func searches(srv *jiratest.Server) []string {
+	var jql []string
+	for _, r := range srv.Requests() {
+		if u, err := url.Parse(r); err == nil && strings.HasSuffix(u.Path, "/search") {
+			jql = append(jql, u.Query().Get("jql"))
+		}
+	}
+	return jql
+}
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	old := time.Date(2020, time.March, 2, 9, 0, 0, 0, time.UTC)
+	before := jiratest.NewServer(jiratest.Fixture{Issues: []jiratest.Issue{
+		{Key: "A-1", Status: "Open", Sprints: []int{10}, Updated: old},
+		{Key: "A-2", Status: "Open", Sprints: []int{10}, Updated: old},
+		{Key: "A-3", Status: "Open", Sprints: []int{10}, Updated: old},
+	}})
+	defer before.Close()
+	//Since the first fill A-1 is done, A-2 left the sprint and A-3 changed without its update time,
+	//so an incremental update keeps its cached status
+	after := jiratest.NewServer(jiratest.Fixture{Issues: []jiratest.Issue{
+		{Key: "A-1", Status: "Done", Sprints: []int{10}, Updated: time.Now()},
+		{Key: "A-2", Status: "Open", Updated: time.Now()},
+		{Key: "A-3", Status: "In Progress", Sprints: []int{10}, Updated: old},
+	}})
+	defer after.Close()
+
+	got := cachedStatuses(t, cachedClient(t, before, dir, agile.CacheUpdate))
+	if want := map[string]string{"A-1": "Open", "A-2": "Open", "A-3": "Open"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("first fill = %v, want %v", got, want)
+	}
+
+	got = cachedStatuses(t, cachedClient(t, after, dir, agile.CacheUpdate))
+	if want := map[string]string{"A-1": "Done", "A-3": "Open"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("update = %v, want %v", got, want)
+	}
+	//The issues updated since the last sync are downloaded, less the margin for time zones
+	var since time.Time
+	for _, jql := range searches(after) {
+		if v := strings.TrimPrefix(jql, `(sprint = 10) AND updated >= "`); v != jql {
+			since, _ = time.ParseInLocation("2006-01-02 15:04\"", v, time.Local)
+		}
+	}
+	if d := time.Since(since); d < 23*time.Hour || d > 25*time.Hour {
+		t.Errorf("no search for the issues updated since a day ago, searches: %v", searches(after))
+	}
+
+	got = cachedStatuses(t, cachedClient(t, after, dir, agile.CacheRefresh))
+	if want := map[string]string{"A-1": "Done", "A-3": "In Progress"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("refresh = %v, want %v", got, want)
+	}
+
+	offline, err := agile.InitOffline(instance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offline.UseCache(agile.NewCache(dir, instance, agile.CacheOffline))
+	got = cachedStatuses(t, offline)
+	if want := map[string]string{"A-1": "Done", "A-3": "In Progress"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("offline = %v, want %v", got, want)
+	}
+}
+
+func TestCacheOfflineEmpty(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	c, err := agile.InitOffline(instance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.UseCache(agile.NewCache(dir, instance, agile.CacheOffline))
+
+	err = c.SearchWithChangelog(context.Background(), "sprint = 10", func(jira.Issue) error { return nil })
+	if _, ok := err.(*agile.NotCachedError); !ok {
+		t.Errorf("search err = %v, want not cached", err)
+	}
+	_, err = c.GetAllStatuses(context.Background())
+	if _, ok := err.(*agile.NotCachedError); !ok {
+		t.Errorf("statuses err = %v, want not cached", err)
+	}
+}
@@ -0,0 +1,147 @@
+package jira
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// searchPageSize is the amount of issues requested per search page
+const searchPageSize = 50
+
+type searchResult struct {
+	Issues     []json.RawMessage `json:"issues"`
+	StartAt    int               `json:"startAt"`
+	MaxResults int               `json:"maxResults"`
+	Total      int               `json:"total"`
+}
+
+// searchIssue is the issue with the paging details of the inline changelog
+type searchIssue struct {
+	Key       string        `json:"key"`
+	Changelog changelogPage `json:"changelog"`
+}
+type changelogPage struct {
+	StartAt    int                     `json:"startAt"`
+	MaxResults int                     `json:"maxResults"`
+	Total      int                     `json:"total"`
+	Histories  []jira.ChangelogHistory `json:"histories"`
+}
+type changelogValues struct {
+	StartAt    int                     `json:"startAt"`
+	MaxResults int                     `json:"maxResults"`
+	Total      int                     `json:"total"`
+	IsLast     bool                    `json:"isLast"`
+	Values     []jira.ChangelogHistory `json:"values"`
+}
+
+// fullIssue is the issue as received from JIRA with its complete changelog
+type fullIssue struct {
+	Issue     json.RawMessage         `json:"issue"`
+	Histories []jira.ChangelogHistory `json:"histories"`
+}
+
+func (i fullIssue) decode() (jira.Issue, error) {
+	var issue jira.Issue
+	err := json.Unmarshal(i.Issue, &issue)
+	if err != nil {
+		return issue, err
+	}
+	issue.Changelog = &jira.Changelog{Histories: i.Histories}
+	return issue, nil
+}
+
+// SearchWithChangelog searches for issues matching the JQL and calls f with every issue.
+// The issues contain their complete changelog, even if JIRA truncated the changelog in the search results.
+// With a cache only the issues updated since the last search are downloaded, see Cache.
+func (c *Client) SearchWithChangelog(ctx context.Context, jql string, f func(jira.Issue) error) error {
+	if c.cache != nil {
+		return c.cache.search(ctx, c, jql, f)
+	}
+	return c.searchWithChangelog(ctx, jql, func(key string, i fullIssue) error {
+		issue, err := i.decode()
+		if err != nil {
+			return fmt.Errorf("could not decode issue %s: %v", key, err)
+		}
+		return f(issue)
+	})
+}
+
+func (c *Client) searchWithChangelog(ctx context.Context, jql string, f func(string, fullIssue) error) error {
+	startAt := 0
+	for {
+		apiEndpoint := fmt.Sprintf("rest/api/2/search?jql=%s&startAt=%d&maxResults=%d&expand=changelog", url.QueryEscape(jql), startAt, searchPageSize)
+		var result searchResult
+		err := c.fetch(ctx, apiEndpoint, &result)
+		if err != nil {
+			return err
+		}
+
+		for _, raw := range result.Issues {
+			var i searchIssue
+			err = json.Unmarshal(raw, &i)
+			if err != nil {
+				return fmt.Errorf("could not decode issue from %s: %v", apiEndpoint, err)
+			}
+			histories := i.Changelog.Histories
+			if i.Changelog.Total > len(histories) {
+				//Changelog was truncated, get the full history
+				histories, err = c.GetChangelog(ctx, i.Key)
+				if err != nil {
+					return err
+				}
+			}
+			err = f(i.Key, fullIssue{raw, histories})
+			if err != nil {
+				return err
+			}
+		}
+
+		startAt = result.StartAt + len(result.Issues)
+		if len(result.Issues) == 0 || startAt >= result.Total {
+			return nil
+		}
+	}
+}
+
+// searchKeys gives the keys of all issues matching the JQL
+func (c *Client) searchKeys(ctx context.Context, jql string) ([]string, error) {
+	var keys []string
+	for {
+		apiEndpoint := fmt.Sprintf("rest/api/2/search?jql=%s&startAt=%d&maxResults=%d&fields=key", url.QueryEscape(jql), len(keys), searchPageSize)
+		var result struct {
+			Issues []searchIssue `json:"issues"`
+			Total  int           `json:"total"`
+		}
+		err := c.fetch(ctx, apiEndpoint, &result)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range result.Issues {
+			keys = append(keys, i.Key)
+		}
+		if len(result.Issues) == 0 || len(keys) >= result.Total {
+			return keys, nil
+		}
+	}
+}
+
+// GetChangelog retrieves all the change history of the issue
+func (c *Client) GetChangelog(ctx context.Context, issueKey string) ([]jira.ChangelogHistory, error) {
+	var histories []jira.ChangelogHistory
+	for {
+		apiEndpoint := fmt.Sprintf("rest/api/2/issue/%s/changelog?startAt=%d", issueKey, len(histories))
+		var result changelogValues
+		err := c.fetch(ctx, apiEndpoint, &result)
+		if err != nil {
+			return nil, err
+		}
+		histories = append(histories, result.Values...)
+		if result.IsLast || len(result.Values) == 0 || len(histories) >= result.Total {
+			return histories, nil
+		}
+	}
+}
@@ -1,11 +1,10 @@
 package jira
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
-
-	jira "gopkg.in/andygrunwald/go-jira.v1"
 )
 
 type BoardInfo struct {
@@ -13,12 +12,50 @@ type BoardInfo struct {
 	Name           string
 	WeekDays       map[time.Weekday]bool
 	NonWorkingDays []time.Time
+	// Estimation is the statistic the board uses to estimate issues
+	Estimation EstimationField
+	// Columns of the board from left to right
+	Columns []Column
+}
+
+// Column is a board column with the names of the statuses mapped to it
+type Column struct {
+	Name     string
+	Statuses []string
+}
+
+// EstimationField describes the issue field used as the board estimation statistic
+type EstimationField struct {
+	// FieldID is the JIRA field ID (e.g. customfield_10002), empty when the board counts issues
+	FieldID string
+	// Name is the field name as it appears in the issue changelog
+	Name string
 }
 
 type boardInfo struct {
-	ID                int               `json:"id"`
-	Name              string            `json:"name"`
-	WorkingDaysConfig workingDaysConfig `json:"workingDaysConfig"`
+	ID                        int                       `json:"id"`
+	Name                      string                    `json:"name"`
+	WorkingDaysConfig         workingDaysConfig         `json:"workingDaysConfig"`
+	EstimationStatisticConfig estimationStatisticConfig `json:"estimationStatisticConfig"`
+	RapidListConfig           rapidListConfig           `json:"rapidListConfig"`
+}
+type rapidListConfig struct {
+	MappedColumns []mappedColumn `json:"mappedColumns"`
+}
+type mappedColumn struct {
+	Name           string         `json:"name"`
+	MappedStatuses []mappedStatus `json:"mappedStatuses"`
+}
+type mappedStatus struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+type estimationStatisticConfig struct {
+	CurrentEstimationStatistic estimationStatistic `json:"currentEstimationStatistic"`
+}
+type estimationStatistic struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
 }
 type workingDaysConfig struct {
 	WeekDays       map[string]interface{} `json:"weekDays"`
@@ -40,17 +77,13 @@ func (d *date) UnmarshalJSON(b []byte) error {
 }
 
 // GetBoardInfo retrieves the data about the board
-func (c *Client) GetBoardInfo(id int) (BoardInfo, error) {
+func (c *Client) GetBoardInfo(ctx context.Context, id int) (BoardInfo, error) {
 	apiEndpoint := "rest/greenhopper/1.0/rapidviewconfig/editmodel.json?rapidViewId=%v"
-	req, err := c.NewRequest("GET", fmt.Sprintf(apiEndpoint, id), nil)
-	if err != nil {
-		return BoardInfo{}, err
-	}
 
 	var result boardInfo
-	resp, err := c.Do(req, &result)
+	err := c.get(ctx, fmt.Sprintf(apiEndpoint, id), &result)
 	if err != nil {
-		return BoardInfo{}, jira.NewJiraError(resp, err)
+		return BoardInfo{}, err
 	}
 
 	return convertToPublic(result), nil
@@ -82,10 +115,30 @@ func convertToPublic(b boardInfo) BoardInfo {
 		nonWork = append(nonWork, time.Time(v.Date))
 	}
 
+	//Statistic IDs are in the form 'field_customfield_10002' or 'issueCount'
+	stat := b.EstimationStatisticConfig.CurrentEstimationStatistic
+	var fieldID string
+	if strings.HasPrefix(stat.ID, "field_") {
+		fieldID = strings.TrimPrefix(stat.ID, "field_")
+	}
+	columns := make([]Column, 0, len(b.RapidListConfig.MappedColumns))
+	for _, c := range b.RapidListConfig.MappedColumns {
+		col := Column{Name: c.Name}
+		for _, s := range c.MappedStatuses {
+			col.Statuses = append(col.Statuses, s.Name)
+		}
+		columns = append(columns, col)
+	}
+
 	return BoardInfo{
 		ID:             b.ID,
 		Name:           b.Name,
 		WeekDays:       weekdays,
 		NonWorkingDays: nonWork,
+		Estimation: EstimationField{
+			FieldID: fieldID,
+			Name:    stat.Name,
+		},
+		Columns: columns,
 	}
 }
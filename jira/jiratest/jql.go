@@ -0,0 +1,364 @@
+package jiratest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// matcher tells if an issue matches a parsed JQL query
+type matcher func(Issue) bool
+
+// parseJQL parses the subset of JQL used by the reports: AND, OR, NOT and parentheses over the
+// fields sprint, fixVersion, "Epic Link", parent, filter, updated, key, project, status, resolution and assignee with =, !=, >=, <=, >, <, in, not in and is (not) EMPTY.
+// WAS is supported for sprint and fixVersion, from the current value and the changelog.
+// Dates are in UTC, like for a JIRA user in the UTC time zone.
+func (s *Server) parseJQL(jql string) (matcher, error) {
+	if i := strings.Index(strings.ToLower(jql), "order by"); i >= 0 {
+		jql = jql[:i]
+	}
+	tokens, err := tokenize(jql)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, server: s}
+	m, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected '%s' in JQL '%s'", p.tokens[p.pos], jql)
+	}
+	return m, nil
+}
+
+func tokenize(jql string) ([]string, error) {
+	var tokens []string
+	r := []rune(jql)
+	for i := 0; i < len(r); {
+		switch c := r[i]; {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			end := i + 1
+			for end < len(r) && r[end] != c {
+				end++
+			}
+			if end == len(r) {
+				return nil, fmt.Errorf("unterminated string in JQL '%s'", jql)
+			}
+			tokens = append(tokens, string(r[i:end+1]))
+			i = end + 1
+		case strings.ContainsRune("(),", c):
+			tokens = append(tokens, string(c))
+			i++
+		case strings.ContainsRune("=!<>", c):
+			end := i + 1
+			if end < len(r) && r[end] == '=' {
+				end++
+			}
+			tokens = append(tokens, string(r[i:end]))
+			i = end
+		default:
+			end := i
+			for end < len(r) && !unicode.IsSpace(r[end]) && !strings.ContainsRune("(),=!<>\"'", r[end]) {
+				end++
+			}
+			tokens = append(tokens, string(r[i:end]))
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type parser struct {
+	tokens []string
+	pos    int
+	server *Server
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *parser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// keyword consumes the next token if it is the keyword, ignoring case
+func (p *parser) keyword(k string) bool {
+	if strings.EqualFold(p.peek(), k) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) or() (matcher, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(i Issue) bool { return l(i) || right(i) }
+	}
+	return left, nil
+}
+
+func (p *parser) and() (matcher, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(i Issue) bool { return l(i) && right(i) }
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (matcher, error) {
+	if p.keyword("NOT") {
+		m, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return func(i Issue) bool { return !m(i) }, nil
+	}
+	if p.peek() == "(" {
+		p.next()
+		m, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing ')' in JQL")
+		}
+		return m, nil
+	}
+	return p.clause()
+}
+
+func (p *parser) clause() (matcher, error) {
+	field := strings.ToLower(unquote(p.next()))
+	values, err := p.server.fieldValues(field)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case p.keyword("is"):
+		not := p.keyword("not")
+		if !p.keyword("EMPTY") && !p.keyword("null") {
+			return nil, fmt.Errorf("expected EMPTY after 'is' for field '%s'", field)
+		}
+		return func(i Issue) bool { return (len(values(i)) == 0) != not }, nil
+	case p.keyword("in"):
+		return p.in(field, values, false)
+	case p.keyword("not"):
+		if !p.keyword("in") {
+			return nil, fmt.Errorf("expected 'in' after 'not' for field '%s'", field)
+		}
+		return p.in(field, values, true)
+	case p.keyword("was"):
+		return wasClause(field, values, unquote(p.next()))
+	}
+	op := p.next()
+	value := unquote(p.next())
+	if field == "updated" {
+		return updatedClause(op, value)
+	}
+	if field == "filter" {
+		return p.server.filterClause(op, value)
+	}
+	switch op {
+	case "=":
+		return func(i Issue) bool { return contains(values(i), value) }, nil
+	case "!=":
+		return func(i Issue) bool { return len(values(i)) > 0 && !contains(values(i), value) }, nil
+	}
+	return nil, fmt.Errorf("unsupported operator '%s' for field '%s'", op, field)
+}
+
+// changelogFields are the changelog field names of the fields supported by WAS
+var changelogFields = map[string]string{"sprint": "Sprint", "fixversion": "Fix Version"}
+
+// wasClause matches issues with the value now or on either side of a change of the field in their changelog
+func wasClause(field string, values func(Issue) []string, value string) (matcher, error) {
+	name, ok := changelogFields[field]
+	if !ok {
+		return nil, fmt.Errorf("unsupported operator 'WAS' for field '%s'", field)
+	}
+	return func(i Issue) bool {
+		if contains(values(i), value) {
+			return true
+		}
+		for _, c := range i.Changelog {
+			for _, it := range c.Items {
+				if it.Field != name {
+					continue
+				}
+				for _, ids := range []interface{}{it.From, it.To} {
+					s, _ := ids.(string)
+					for _, id := range strings.Split(s, ",") {
+						if strings.TrimSpace(id) == value {
+							return true
+						}
+					}
+				}
+			}
+		}
+		return false
+	}, nil
+}
+
+func (p *parser) in(field string, values func(Issue) []string, not bool) (matcher, error) {
+	if p.next() != "(" {
+		return nil, fmt.Errorf("expected '(' after 'in' for field '%s'", field)
+	}
+	var list []string
+	for {
+		list = append(list, unquote(p.next()))
+		t := p.next()
+		if t == ")" {
+			break
+		}
+		if t != "," {
+			return nil, fmt.Errorf("expected ',' or ')' in list for field '%s'", field)
+		}
+	}
+	return func(i Issue) bool {
+		for _, v := range list {
+			if contains(values(i), v) {
+				return !not
+			}
+		}
+		return not
+	}, nil
+}
+
+// fieldValues gives the accessor of the values of the issue field for comparison
+func (s *Server) fieldValues(field string) (func(Issue) []string, error) {
+	switch field {
+	case "sprint":
+		return func(i Issue) []string {
+			var ids []string
+			for _, id := range i.Sprints {
+				ids = append(ids, strconv.Itoa(id))
+			}
+			return ids
+		}, nil
+	case "fixversion":
+		return func(i Issue) []string {
+			var ids []string
+			for _, id := range i.FixVersions {
+				ids = append(ids, strconv.Itoa(id))
+			}
+			return ids
+		}, nil
+	case "epic link":
+		return func(i Issue) []string { return nonEmpty(i.Epic) }, nil
+	case "parent":
+		return func(i Issue) []string { return nonEmpty(i.Parent) }, nil
+	case "key", "issuekey":
+		return func(i Issue) []string { return []string{i.Key} }, nil
+	case "project":
+		return func(i Issue) []string { return []string{projectOf(i.Key)} }, nil
+	case "status":
+		return func(i Issue) []string { return []string{i.Status} }, nil
+	case "resolution":
+		return func(i Issue) []string { return nonEmpty(s.resolution(i)) }, nil
+	case "assignee":
+		return func(i Issue) []string { return nonEmpty(userName(i.Assignee)) }, nil
+	case "updated", "filter":
+		return func(Issue) []string { return nil }, nil
+	}
+	return nil, fmt.Errorf("field '%s' is not supported by the fake JIRA", field)
+}
+
+// nonEmpty gives the value as the only value, or no values if empty
+func nonEmpty(v string) []string {
+	if v == "" {
+		return nil
+	}
+	return []string{v}
+}
+
+func (s *Server) filterClause(op, value string) (matcher, error) {
+	if op != "=" {
+		return nil, fmt.Errorf("unsupported operator '%s' for field 'filter'", op)
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, fmt.Errorf("filter must be given by ID, got '%s'", value)
+	}
+	for _, b := range s.fixture.Boards {
+		if b.FilterID != id {
+			continue
+		}
+		if b.Filter == "" {
+			return func(Issue) bool { return true }, nil
+		}
+		return s.parseJQL(b.Filter)
+	}
+	return nil, fmt.Errorf("filter %d does not exist", id)
+}
+
+func updatedClause(op, value string) (matcher, error) {
+	t, err := time.ParseInLocation("2006-01-02 15:04", value, time.UTC)
+	if err != nil {
+		t, err = time.ParseInLocation("2006-01-02", value, time.UTC)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse date '%s'", value)
+	}
+	switch op {
+	case ">=":
+		return func(i Issue) bool { return !i.updated().Before(t) }, nil
+	case ">":
+		return func(i Issue) bool { return i.updated().After(t) }, nil
+	case "<=":
+		return func(i Issue) bool { return !i.updated().After(t) }, nil
+	case "<":
+		return func(i Issue) bool { return i.updated().Before(t) }, nil
+	}
+	return nil, fmt.Errorf("unsupported operator '%s' for field 'updated'", op)
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if strings.EqualFold(s, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func projectOf(key string) string {
+	if i := strings.LastIndex(key, "-"); i >= 0 {
+		return key[:i]
+	}
+	return key
+}
@@ -0,0 +1,540 @@
+// Package jiratest provides an in-process fake JIRA server for testing the reports against declarative fixtures.
+package jiratest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	agile "reports/jira"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// Fixture is the content of the fake JIRA instance
+type Fixture struct {
+	Boards  []Board
+	Sprints []jira.Sprint
+	// Statuses with their status category, e.g. jira.StatusCategory{Key: jira.StatusCategoryComplete}
+	Statuses []jira.Status
+	Issues   []Issue
+	Versions []Version
+	// ChangelogLimit truncates the changelogs in the search results like JIRA does, 0 does not truncate
+	ChangelogLimit int
+}
+
+// Board is an agile board with its configuration
+type Board struct {
+	jira.Board
+	// Project is the key of the project the board is located in
+	Project string
+	// Filter is the JQL of the board filter, empty matches all issues
+	Filter string
+	// WeekDays are the working days, default Monday to Friday
+	WeekDays []time.Weekday
+	// NonWorkingDays are holidays as YYYY-MM-DD
+	NonWorkingDays []string
+	// Estimation is the field used to estimate issues, default remaining time
+	Estimation agile.EstimationField
+	// Columns of the board from left to right
+	Columns []agile.Column
+}
+
+// Version is a project version, the fixVersion of issues
+type Version struct {
+	ID int
+	// Project is the key of the project of the version
+	Project  string
+	Name     string
+	Released bool
+	// StartDate and ReleaseDate are YYYY-MM-DD, empty if not set
+	StartDate, ReleaseDate string
+}
+
+// Issue is an issue with its changelog
+type Issue struct {
+	Key    string
+	Status string
+	// Sprints are the IDs of the sprints the issue is in now
+	Sprints []int
+	// FixVersions are the IDs of the versions the issue is fixed in now
+	FixVersions []int
+	// Epic is the key of the epic of the issue by its Epic Link
+	Epic string
+	// Parent is the key of the parent of a sub-task, or of the epic in next-gen projects
+	Parent string
+	// Assignee is the display name of the assignee, empty if unassigned
+	Assignee string
+	// Fields are the other issue fields by ID, e.g. timeestimate or customfield_10002
+	Fields map[string]interface{}
+	// Updated is the last update, defaults to the time of the last change
+	Updated time.Time
+	// Created is the creation time, omitted from the issue if zero
+	Created time.Time
+	// Changelog is the change history, oldest first
+	Changelog []Change
+}
+
+// Change is an entry in the change history of an issue
+type Change struct {
+	Created time.Time
+	Items   []jira.ChangelogItems
+}
+
+// StatusChange gives the changelog item of a status transition
+func StatusChange(from, to string) jira.ChangelogItems {
+	return jira.ChangelogItems{Field: "status", FieldType: "jira", FromString: from, ToString: to}
+}
+
+// AssigneeChange gives the changelog item of assigning the issue, empty for unassigned.
+// The display names are used as user names.
+func AssigneeChange(from, to string) jira.ChangelogItems {
+	return jira.ChangelogItems{Field: "assignee", FieldType: "jira", From: from, FromString: from, To: to, ToString: to}
+}
+
+// FieldChange gives the changelog item of a numeric field like timeestimate, timespent or story points
+func FieldChange(field string, from, to interface{}) jira.ChangelogItems {
+	return jira.ChangelogItems{Field: field, FieldType: "jira", From: fmt.Sprint(from), FromString: fmt.Sprint(from), To: fmt.Sprint(to), ToString: fmt.Sprint(to)}
+}
+
+// SprintChange gives the changelog item of moving the issue between sprints
+func SprintChange(from, to []int) jira.ChangelogItems {
+	ids := func(sprints []int) string {
+		var s []string
+		for _, id := range sprints {
+			s = append(s, strconv.Itoa(id))
+		}
+		return strings.Join(s, ", ")
+	}
+	return jira.ChangelogItems{Field: "Sprint", FieldType: "custom", From: ids(from), To: ids(to)}
+}
+
+// FixVersionChange gives the changelog item of adding the issue to a fix version, or removing it with to 0.
+// JIRA logs a separate item for every version added or removed.
+func FixVersionChange(from, to int) jira.ChangelogItems {
+	it := jira.ChangelogItems{Field: "Fix Version", FieldType: "jira"}
+	if from != 0 {
+		it.From, it.FromString = strconv.Itoa(from), "Version "+strconv.Itoa(from)
+	}
+	if to != 0 {
+		it.To, it.ToString = strconv.Itoa(to), "Version "+strconv.Itoa(to)
+	}
+	return it
+}
+
+func (i Issue) updated() time.Time {
+	if !i.Updated.IsZero() || len(i.Changelog) == 0 {
+		return i.Updated
+	}
+	return i.Changelog[len(i.Changelog)-1].Created
+}
+
+// Server is the fake JIRA, serving the agile, greenhopper, status, project version and search endpoints used by the reports
+type Server struct {
+	*httptest.Server
+	fixture  Fixture
+	mu       sync.Mutex
+	requests []string
+}
+
+// NewServer starts the fake JIRA serving the fixture, to be closed by the caller
+func NewServer(f Fixture) *Server {
+	s := &Server{fixture: f}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Requests gives the request URIs received so far
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.RequestURI())
+	s.mu.Unlock()
+
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	var v interface{}
+	var err error
+	switch {
+	case match(path, "rest", "agile", "1.0", "board"):
+		v, err = s.boards(r)
+	case match(path, "rest", "agile", "1.0", "board", "*"):
+		v, err = s.board(path[4])
+	case match(path, "rest", "agile", "1.0", "board", "*", "sprint"):
+		v, err = s.boardSprints(path[4], r)
+	case match(path, "rest", "agile", "1.0", "sprint", "*"):
+		v, err = s.sprint(path[4])
+	case match(path, "rest", "greenhopper", "1.0", "rapidviewconfig", "editmodel.json"):
+		v, err = s.boardConfig(r.URL.Query().Get("rapidViewId"))
+	case match(path, "rest", "api", "2", "status"):
+		v = s.fixture.Statuses
+	case match(path, "rest", "api", "2", "project", "*", "versions"):
+		v = s.versions(path[4])
+	case match(path, "rest", "api", "2", "search"):
+		v, err = s.search(r)
+	case match(path, "rest", "api", "2", "issue", "*", "changelog"):
+		v, err = s.changelog(path[4], r)
+	default:
+		err = notFound("endpoint " + r.URL.Path)
+	}
+	if err != nil {
+		status := http.StatusBadRequest
+		if _, ok := err.(notFound); ok {
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, map[string]interface{}{"errorMessages": []string{err.Error()}, "errors": map[string]string{}})
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+type notFound string
+
+func (e notFound) Error() string {
+	return string(e) + " does not exist"
+}
+
+// match tells if the path matches the pattern, '*' matching any element
+func match(path []string, pattern ...string) bool {
+	if len(path) != len(pattern) {
+		return false
+	}
+	for i, p := range pattern {
+		if p != "*" && p != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// page gives the range of n values requested by startAt and maxResults
+func page(r *http.Request, n, defaultMax int) (start, end int) {
+	q := r.URL.Query()
+	start, _ = strconv.Atoi(q.Get("startAt"))
+	max, err := strconv.Atoi(q.Get("maxResults"))
+	if err != nil || max <= 0 {
+		max = defaultMax
+	}
+	if start > n {
+		start = n
+	}
+	end = start + max
+	if end > n {
+		end = n
+	}
+	return start, end
+}
+
+func (s *Server) boards(r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	var boards []jira.Board
+	for _, b := range s.fixture.Boards {
+		if t := q.Get("type"); t != "" && t != b.Type {
+			continue
+		}
+		if n := q.Get("name"); n != "" && !strings.Contains(strings.ToLower(b.Name), strings.ToLower(n)) {
+			continue
+		}
+		if p := q.Get("projectKeyOrId"); p != "" && !strings.EqualFold(p, b.Project) {
+			continue
+		}
+		boards = append(boards, b.Board)
+	}
+	start, end := page(r, len(boards), 50)
+	return map[string]interface{}{
+		"maxResults": 50,
+		"startAt":    start,
+		"total":      len(boards),
+		"isLast":     end == len(boards),
+		"values":     nonNil(boards[start:end]),
+	}, nil
+}
+
+func (s *Server) findBoard(id string) (Board, error) {
+	for _, b := range s.fixture.Boards {
+		if strconv.Itoa(b.ID) == id {
+			return b, nil
+		}
+	}
+	return Board{}, notFound("board " + id)
+}
+
+func (s *Server) board(id string) (interface{}, error) {
+	b, err := s.findBoard(id)
+	return b.Board, err
+}
+
+func (s *Server) boardSprints(id string, r *http.Request) (interface{}, error) {
+	b, err := s.findBoard(id)
+	if err != nil {
+		return nil, err
+	}
+	states := r.URL.Query().Get("state")
+	var sprints []jira.Sprint
+	for _, sp := range s.fixture.Sprints {
+		if sp.OriginBoardID != b.ID {
+			continue
+		}
+		if states != "" && !contains(strings.Split(states, ","), sp.State) {
+			continue
+		}
+		sprints = append(sprints, sp)
+	}
+	start, end := page(r, len(sprints), 50)
+	return map[string]interface{}{
+		"maxResults": 50,
+		"startAt":    start,
+		"isLast":     end == len(sprints),
+		"values":     nonNil(sprints[start:end]),
+	}, nil
+}
+
+func (s *Server) sprint(id string) (interface{}, error) {
+	for _, sp := range s.fixture.Sprints {
+		if strconv.Itoa(sp.ID) == id {
+			return sp, nil
+		}
+	}
+	return nil, notFound("sprint " + id)
+}
+
+func (s *Server) boardConfig(id string) (interface{}, error) {
+	b, err := s.findBoard(id)
+	if err != nil {
+		return nil, err
+	}
+	weekDays := b.WeekDays
+	if weekDays == nil {
+		weekDays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	}
+	days := make(map[string]interface{})
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		days[strings.ToLower(d.String())] = false
+	}
+	for _, d := range weekDays {
+		days[strings.ToLower(d.String())] = true
+	}
+	var holidays []map[string]string
+	for _, d := range b.NonWorkingDays {
+		holidays = append(holidays, map[string]string{"iso8601Date": d})
+	}
+	stat := map[string]string{"id": "field_timeestimate", "name": "Remaining Estimate"}
+	if b.Estimation.Name != "" {
+		stat = map[string]string{"id": "field_" + b.Estimation.FieldID, "name": b.Estimation.Name}
+		if b.Estimation.FieldID == "" {
+			stat["id"] = "issueCount"
+		}
+	}
+	var columns []map[string]interface{}
+	for _, c := range b.Columns {
+		var statuses []map[string]string
+		for _, st := range c.Statuses {
+			statuses = append(statuses, map[string]string{"id": st, "name": st})
+		}
+		columns = append(columns, map[string]interface{}{"name": c.Name, "mappedStatuses": statuses})
+	}
+	return map[string]interface{}{
+		"id":                        b.ID,
+		"name":                      b.Name,
+		"workingDaysConfig":         map[string]interface{}{"weekDays": days, "nonWorkingDays": holidays},
+		"estimationStatisticConfig": map[string]interface{}{"currentEstimationStatistic": stat},
+		"rapidListConfig":           map[string]interface{}{"mappedColumns": columns},
+	}, nil
+}
+
+func (s *Server) search(r *http.Request) (interface{}, error) {
+	q := r.URL.Query()
+	m, err := s.parseJQL(q.Get("jql"))
+	if err != nil {
+		return nil, err
+	}
+	var found []Issue
+	for _, i := range s.fixture.Issues {
+		if m(i) {
+			found = append(found, i)
+		}
+	}
+	start, end := page(r, len(found), 50)
+	issues := make([]interface{}, 0, end-start)
+	for _, i := range found[start:end] {
+		if q.Get("fields") == "key" {
+			issues = append(issues, map[string]string{"key": i.Key})
+		} else {
+			issues = append(issues, s.issue(i, strings.Contains(q.Get("expand"), "changelog")))
+		}
+	}
+	return map[string]interface{}{
+		"startAt":    start,
+		"maxResults": 50,
+		"total":      len(found),
+		"issues":     issues,
+	}, nil
+}
+
+func (s *Server) issue(i Issue, changelog bool) map[string]interface{} {
+	fields := map[string]interface{}{
+		"summary": "Summary of " + i.Key,
+		"status":  s.status(i.Status),
+		"updated": jiraTime(i.updated()),
+	}
+	if !i.Created.IsZero() {
+		fields["created"] = jiraTime(i.Created)
+	}
+	if i.Assignee != "" {
+		fields["assignee"] = user(i.Assignee)
+	}
+	if i.Parent != "" {
+		fields["parent"] = map[string]string{"key": i.Parent}
+	}
+	if len(i.FixVersions) > 0 {
+		var versions []map[string]string
+		for _, id := range i.FixVersions {
+			versions = append(versions, map[string]string{"id": strconv.Itoa(id)})
+		}
+		fields["fixVersions"] = versions
+	}
+	for k, v := range i.Fields {
+		fields[k] = v
+	}
+	result := map[string]interface{}{
+		"id":     strconv.Itoa(s.issueID(i.Key)),
+		"key":    i.Key,
+		"fields": fields,
+	}
+	if changelog {
+		histories := s.histories(i)
+		total := len(histories)
+		if l := s.fixture.ChangelogLimit; l > 0 && total > l {
+			histories = histories[:l]
+		}
+		result["changelog"] = map[string]interface{}{
+			"startAt":    0,
+			"maxResults": len(histories),
+			"total":      total,
+			"histories":  histories,
+		}
+	}
+	return result
+}
+
+func (s *Server) versions(project string) interface{} {
+	result := []map[string]interface{}{}
+	for _, v := range s.fixture.Versions {
+		if v.Project != project {
+			continue
+		}
+		version := map[string]interface{}{"id": strconv.Itoa(v.ID), "name": v.Name, "released": v.Released}
+		if v.StartDate != "" {
+			version["startDate"] = v.StartDate
+		}
+		if v.ReleaseDate != "" {
+			version["releaseDate"] = v.ReleaseDate
+		}
+		result = append(result, version)
+	}
+	return result
+}
+
+// user gives the user object of JIRA Server for the display name, e.g. alice.smith for Alice Smith
+func user(displayName string) map[string]string {
+	name := userName(displayName)
+	return map[string]string{"name": name, "key": name, "displayName": displayName, "emailAddress": name + "@example.org"}
+}
+
+// userName gives the user name of JIRA Server for the display name
+func userName(displayName string) string {
+	return strings.ToLower(strings.Replace(displayName, " ", ".", -1))
+}
+
+func (s *Server) issueID(key string) int {
+	for n, i := range s.fixture.Issues {
+		if i.Key == key {
+			return 10000 + n
+		}
+	}
+	return 0
+}
+
+func (s *Server) status(name string) interface{} {
+	for _, st := range s.fixture.Statuses {
+		if st.Name == name {
+			return st
+		}
+	}
+	return map[string]string{"name": name}
+}
+
+// resolution gives the resolution of the issue, Done in the statuses of the complete category
+func (s *Server) resolution(i Issue) string {
+	for _, st := range s.fixture.Statuses {
+		if st.Name == i.Status && st.StatusCategory.Key == jira.StatusCategoryComplete {
+			return "Done"
+		}
+	}
+	return ""
+}
+
+func (s *Server) histories(i Issue) []map[string]interface{} {
+	histories := make([]map[string]interface{}, 0, len(i.Changelog))
+	for n, c := range i.Changelog {
+		histories = append(histories, map[string]interface{}{
+			"id":      strconv.Itoa(s.issueID(i.Key)*1000 + n),
+			"author":  user("Fake User"),
+			"created": jiraTime(c.Created),
+			"items":   c.Items,
+		})
+	}
+	return histories
+}
+
+func (s *Server) changelog(key string, r *http.Request) (interface{}, error) {
+	for _, i := range s.fixture.Issues {
+		if i.Key != key {
+			continue
+		}
+		histories := s.histories(i)
+		start, end := page(r, len(histories), 100)
+		return map[string]interface{}{
+			"startAt":    start,
+			"maxResults": 100,
+			"total":      len(histories),
+			"isLast":     end == len(histories),
+			"values":     histories[start:end],
+		}, nil
+	}
+	return nil, notFound("issue " + key)
+}
+
+// jiraTime formats the time like JIRA does in issues and changelogs
+func jiraTime(t time.Time) string {
+	return t.Format("2006-01-02T15:04:05.000-0700")
+}
+
+// nonNil makes empty lists encode as [] instead of null
+func nonNil(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []jira.Board:
+		if v == nil {
+			return []jira.Board{}
+		}
+	case []jira.Sprint:
+		if v == nil {
+			return []jira.Sprint{}
+		}
+	}
+	return v
+}
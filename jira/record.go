@@ -0,0 +1,338 @@
+package jira
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// Option wraps the HTTP transport of the client created by InitJira
+type Option func(http.RoundTripper) http.RoundTripper
+
+// Record stores sanitized copies of all responses in dir, so the session can be replayed with InitReplay.
+// User names, e-mail addresses, summaries, descriptions and comments are replaced, the instance URL is
+// replaced by https://jira.example.com. The pseudonyms of the users are keyed with a random key of the session
+// that is never stored, so they cannot be matched against known names and differ between sessions.
+// The JQL of the recorded requests is replaced by a pseudonym as well, as it can hold user names.
+func Record(dir, instanceURL string) (Option, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("could not generate the pseudonym key: %v", err)
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return &recordTransport{
+			dir:         dir,
+			instanceURL: strings.TrimSuffix(instanceURL, "/"),
+			next:        next,
+			key:         key,
+			users:       make(map[string]bool),
+			userFields:  make(map[string]bool),
+		}
+	}, nil
+}
+
+// InitReplay creates the JIRA client instance serving the responses recorded in dir, without contacting JIRA
+func InitReplay(dir, url string) (*Client, error) {
+	jiraClient, err := jira.NewClient(&http.Client{Transport: &replayTransport{dir}}, url)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{Client: jiraClient}, nil
+}
+
+// exampleURL replaces the instance URL in the recorded responses
+const exampleURL = "https://jira.example.com"
+
+// recording is a recorded response, stored in a file named after the hash of the request
+type recording struct {
+	Method      string `json:"method"`
+	URL         string `json:"url"`
+	StatusCode  int    `json:"statusCode"`
+	ContentType string `json:"contentType"`
+	// Body is the JSON response, or a string with the body if it isn't JSON
+	Body json.RawMessage `json:"body"`
+}
+
+func recordingFile(dir string, req *http.Request) string {
+	return filepath.Join(dir, hash(req.Method+" "+req.URL.RequestURI())+".json")
+}
+
+type recordTransport struct {
+	dir, instanceURL string
+	next             http.RoundTripper
+	// key is the HMAC key of the pseudonyms
+	key []byte
+
+	mu sync.Mutex
+	// users are the names, keys, display names, e-mail addresses and account IDs of the users seen in the session
+	users map[string]bool
+	// userFields are the IDs and names of the fields seen holding users in the session, besides systemUserFields
+	userFields map[string]bool
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	r := recording{
+		Method:      req.Method,
+		URL:         t.sanitizeURL(req.URL),
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	r.Body, err = t.sanitize(body)
+	if err != nil {
+		return nil, fmt.Errorf("could not sanitize response of %s: %v", r.URL, err)
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(r)
+	if err != nil {
+		return nil, err
+	}
+	err = writeFileAtomic(recordingFile(t.dir, req), b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("could not record response of %s: %v", r.URL, err)
+	}
+	return resp, nil
+}
+
+type replayTransport struct {
+	dir string
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var r recording
+	found, err := readJSON(recordingFile(t.dir, req), &r)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no recorded response for %s %s", req.Method, req.URL.RequestURI())
+	}
+	body := []byte(r.Body)
+	var text string
+	if json.Unmarshal(r.Body, &text) == nil {
+		body = []byte(text)
+	}
+	header := make(http.Header)
+	header.Set("Content-Type", r.ContentType)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// systemUserFields are the issue fields holding users, their changelog values are replaced like the users
+var systemUserFields = map[string]bool{"assignee": true, "reporter": true, "creator": true}
+
+// userIDs are the properties of a user object identifying the user
+var userIDs = []string{"name", "key", "displayName", "emailAddress", "accountId"}
+
+// itemValues are the properties of a changelog item with the old and new value
+var itemValues = []string{"from", "to", "fromString", "toString"}
+
+// textFields are the issue fields with free text, their values and changelog values are removed
+var textFields = map[string]bool{"description": true, "environment": true, "comment": true}
+
+// sanitize replaces the personal data and free text of the JSON body, other bodies are stored as a string
+func (t *recordTransport) sanitize(body []byte) (json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return json.Marshal(t.sanitizeString(string(body)))
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.learnUsers(v)
+	return json.Marshal(t.sanitizeValue(v))
+}
+
+// sanitizeURL replaces the JQL of the request by a pseudonym
+func (t *recordTransport) sanitizeURL(u *url.URL) string {
+	q := u.Query()
+	jql, ok := q["jql"]
+	if !ok {
+		return u.RequestURI()
+	}
+	q.Set("jql", "jql-"+t.keyed(strings.Join(jql, " ")))
+	return u.Path + "?" + q.Encode()
+}
+
+// learnUsers collects the users and the custom user picker fields of the response. The changelog items
+// of a user field are found by the field ID, or by a value naming a user, as the changelog only has the
+// field name. Changelog items of the field naming users not seen are replaced once the field is known.
+func (t *recordTransport) learnUsers(v interface{}) {
+	walkObjects(v, func(o map[string]interface{}) {
+		if isUser(o) {
+			for _, k := range userIDs {
+				if s, ok := o[k].(string); ok && s != "" {
+					t.users[s] = true
+				}
+			}
+		}
+		if fields, ok := o["fields"].(map[string]interface{}); ok {
+			for k, f := range fields {
+				if holdsUser(f) {
+					t.userFields[k] = true
+				}
+			}
+		}
+	})
+	walkObjects(v, func(o map[string]interface{}) {
+		field, ok := o["field"].(string)
+		if !ok {
+			return
+		}
+		if id, ok := o["fieldId"].(string); ok && t.userFields[id] {
+			t.userFields[field] = true
+		}
+		for _, k := range itemValues {
+			if s, ok := o[k].(string); ok && t.users[s] {
+				t.userFields[field] = true
+			}
+		}
+	})
+}
+
+// walkObjects calls f for all objects in the JSON value, parents before their children
+func walkObjects(v interface{}, f func(map[string]interface{})) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		f(v)
+		for _, e := range v {
+			walkObjects(e, f)
+		}
+	case []interface{}:
+		for _, e := range v {
+			walkObjects(e, f)
+		}
+	}
+}
+
+// isUser tells if the object is a user of JIRA Server or JIRA Cloud
+func isUser(o map[string]interface{}) bool {
+	_, hasName := o["displayName"]
+	return hasName && (o["emailAddress"] != nil || o["avatarUrls"] != nil || o["accountId"] != nil || o["timeZone"] != nil)
+}
+
+// holdsUser tells if the field value is a user or a list of users
+func holdsUser(v interface{}) bool {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return isUser(v)
+	case []interface{}:
+		for _, e := range v {
+			if holdsUser(e) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (t *recordTransport) sanitizeValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		t.sanitizeObject(v)
+		for k, e := range v {
+			v[k] = t.sanitizeValue(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = t.sanitizeValue(e)
+		}
+	case string:
+		return t.sanitizeString(v)
+	}
+	return v
+}
+
+func (t *recordTransport) sanitizeString(s string) string {
+	if t.instanceURL == "" {
+		return s
+	}
+	return strings.Replace(s, t.instanceURL, exampleURL, -1)
+}
+
+func (t *recordTransport) sanitizeObject(o map[string]interface{}) {
+	if isUser(o) {
+		//A user
+		for _, k := range userIDs {
+			if s, ok := o[k].(string); ok {
+				o[k] = t.pseudonym(s)
+			}
+		}
+		delete(o, "avatarUrls")
+	}
+	if field, ok := o["field"].(string); ok {
+		//A changelog item
+		id, _ := o["fieldId"].(string)
+		for _, k := range itemValues {
+			s, ok := o[k].(string)
+			switch {
+			case !ok:
+			case systemUserFields[field] || t.userFields[field] || t.userFields[id]:
+				o[k] = t.pseudonym(s)
+			case textFields[field] || field == "summary":
+				o[k] = ""
+			}
+		}
+	}
+	if fields, ok := o["fields"].(map[string]interface{}); ok {
+		//An issue
+		if _, ok := fields["summary"]; ok {
+			fields["summary"] = fmt.Sprintf("Summary of %v", o["key"])
+		}
+		for k := range textFields {
+			if _, ok := fields[k]; ok {
+				fields[k] = nil
+			}
+		}
+	}
+}
+
+// pseudonym replaces the user name, the same name gives the same pseudonym within the session
+func (t *recordTransport) pseudonym(s string) string {
+	if s == "" {
+		return s
+	}
+	return "user-" + t.keyed(s)
+}
+
+// keyed gives the start of the HMAC of s with the key of the session
+func (t *recordTransport) keyed(s string) string {
+	mac := hmac.New(sha256.New, t.key)
+	mac.Write([]byte(s))
+	return hex.EncodeToString(mac.Sum(nil))[:8]
+}
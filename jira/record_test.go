@@ -0,0 +1,95 @@
+package jira_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	agile "reports/jira"
+	"reports/jira/jiratest"
+	"strings"
+	"testing"
+	"time"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+func TestRecordSanitizes(t *testing.T) {
+	srv := jiratest.NewServer(jiratest.Fixture{
+		Issues: []jiratest.Issue{{
+			Key: "A-1", Status: "Open", Assignee: "Alice Smith",
+			Fields: map[string]interface{}{"customfield_10300": map[string]string{
+				"name": "carol.white", "displayName": "Carol White", "emailAddress": "carol.white@example.org"},
+			},
+			Changelog: []jiratest.Change{{
+				Created: time.Date(2026, time.October, 5, 9, 0, 0, 0, time.UTC),
+				Items: []jira.ChangelogItems{
+					jiratest.AssigneeChange("Bob Jones", "Alice Smith"),
+					//A custom user picker, the former reviewer is only named in the changelog
+					{Field: "Reviewer", FieldType: "custom", From: "dave.brown", FromString: "Dave Brown", To: "carol.white", ToString: "Carol White"},
+				},
+			}},
+		}},
+	})
+	defer srv.Close()
+	dir, err := ioutil.TempDir("", "record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rec, err := agile.Record(dir, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := agile.InitJiraWithAuth(agile.Auth{Mode: agile.BearerToken, Secret: "secret-token"}, srv.URL, rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var assignee string
+	err = c.SearchWithChangelog(context.Background(), "assignee = alice.smith", func(i jira.Issue) error {
+		assignee = i.Fields.Assignee.DisplayName
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assignee != "Alice Smith" {
+		t.Errorf("assignee = %s, the recording must not change the live responses", assignee)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no recording in %s: %v", dir, err)
+	}
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range []string{"Alice", "alice", "Bob", "bob", "Carol", "carol", "Dave", "dave", "Fake User", "@example.org", "secret-token", srv.URL} {
+			if strings.Contains(string(b), s) {
+				t.Errorf("recording %s contains '%s':\n%s", filepath.Base(f), s, b)
+			}
+		}
+	}
+
+	r, err := agile.InitReplay(dir, "https://jira.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var replayed jira.Issue
+	err = r.SearchWithChangelog(context.Background(), "assignee = alice.smith", func(i jira.Issue) error {
+		replayed = i
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//The pseudonym of a user is the same in the issue and in its changelog
+	if to := replayed.Changelog.Histories[0].Items[0].ToString; to != replayed.Fields.Assignee.DisplayName || !strings.HasPrefix(to, "user-") {
+		t.Errorf("changelog assignee = %s, want the pseudonym of the assignee %s", to, replayed.Fields.Assignee.DisplayName)
+	}
+	if from := replayed.Changelog.Histories[0].Items[1].FromString; !strings.HasPrefix(from, "user-") {
+		t.Errorf("changelog reviewer = %s, want a pseudonym", from)
+	}
+}
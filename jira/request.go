@@ -0,0 +1,93 @@
+package jira
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	jira "gopkg.in/andygrunwald/go-jira.v1"
+)
+
+// ErrNoActiveSprint is returned when the board has no active sprint to report on
+var ErrNoActiveSprint = errors.New("there are no active sprints")
+
+// APIError is returned when JIRA responds to a request with an error
+type APIError struct {
+	Endpoint string
+	// StatusCode is the HTTP status of the response, 0 if no response was received
+	StatusCode int
+	Err        error
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("request to %s failed: %v", e.Endpoint, e.Err)
+	}
+	return fmt.Sprintf("request to %s failed with status %d: %v", e.Endpoint, e.StatusCode, e.Err)
+}
+
+// Unwrap gives the underlying JIRA client error
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// NotFoundError is returned when a board or sprint can't be resolved by name
+type NotFoundError struct {
+	// Kind is 'board', 'sprint', 'version' or 'epic'
+	Kind string
+	Name string
+	// Options lists the available choices, if any
+	Options []string
+}
+
+func (e *NotFoundError) Error() string {
+	if e.Name == "" {
+		return fmt.Sprintf("%s must be selected, options are: %s", e.Kind, strings.Join(e.Options, ", "))
+	}
+	return fmt.Sprintf("could not find a matching %s for '%s'", e.Kind, e.Name)
+}
+
+// AmbiguousError is returned when several boards have the given name
+type AmbiguousError struct {
+	// Kind is 'board'
+	Kind string
+	Name string
+	// Options lists the matching choices
+	Options []string
+}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("%d %ss are named '%s', give the ID instead or narrow the search by project or type: %s", len(e.Options), e.Kind, e.Name, strings.Join(e.Options, ", "))
+}
+
+// get requests the API endpoint and decodes the JSON response to v, using the cache if any
+func (c *Client) get(ctx context.Context, apiEndpoint string, v interface{}) error {
+	if c.cache != nil {
+		return c.cache.get(ctx, c, apiEndpoint, v)
+	}
+	return c.fetch(ctx, apiEndpoint, v)
+}
+
+// fetch requests the API endpoint from JIRA and decodes the JSON response to v
+func (c *Client) fetch(ctx context.Context, apiEndpoint string, v interface{}) error {
+	req, err := c.NewRequest("GET", apiEndpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.Do(req.WithContext(ctx), v)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		apiErr := &APIError{Endpoint: apiEndpoint, Err: err}
+		if resp != nil {
+			apiErr.StatusCode = resp.StatusCode
+			if resp.StatusCode < 200 || resp.StatusCode > 299 {
+				apiErr.Err = jira.NewJiraError(resp, err)
+			}
+		}
+		return apiErr
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package jira
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Version is a project version, the fixVersion of issues. The dates are zero if not set.
+type Version struct {
+	ID          string
+	Name        string
+	Released    bool
+	StartDate   time.Time
+	ReleaseDate time.Time
+}
+
+type version struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Released    bool   `json:"released"`
+	StartDate   string `json:"startDate"`
+	ReleaseDate string `json:"releaseDate"`
+}
+
+// GetVersions gets the versions of the project with given key or ID
+func (c *Client) GetVersions(ctx context.Context, project string) ([]Version, error) {
+	apiEndpoint := fmt.Sprintf("rest/api/2/project/%s/versions", url.PathEscape(project))
+
+	var result []version
+	err := c.get(ctx, apiEndpoint, &result)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]Version, 0, len(result))
+	for _, v := range result {
+		pv := Version{ID: v.ID, Name: v.Name, Released: v.Released}
+		pv.StartDate, err = parseDate(v.StartDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start date of version %s: %v", v.Name, err)
+		}
+		pv.ReleaseDate, err = parseDate(v.ReleaseDate)
+		if err != nil {
+			return nil, fmt.Errorf("invalid release date of version %s: %v", v.Name, err)
+		}
+		versions = append(versions, pv)
+	}
+	return versions, nil
+}
+
+// parseDate parses the YYYY-MM-DD dates of versions in local time, empty gives zero time
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("2006-01-02", s, time.Local)
+}
+
+// FindVersion gets the version of the project by name or ID.
+// Without name the unreleased version is taken if there is one, or chosen interactively.
+func (c *Client) FindVersion(ctx context.Context, project, name string, interactive bool) (Version, error) {
+	versions, err := c.GetVersions(ctx, project)
+	if err != nil {
+		return Version{}, err
+	}
+	if name != "" {
+		for _, v := range versions {
+			if v.Name == name || v.ID == name {
+				return v, nil
+			}
+		}
+		return Version{}, &NotFoundError{Kind: "version", Name: name}
+	}
+	var unreleased []interface{}
+	var names []string
+	for _, v := range versions {
+		if !v.Released {
+			unreleased = append(unreleased, v)
+			names = append(names, v.Name)
+		}
+	}
+	if len(unreleased) == 1 {
+		return unreleased[0].(Version), nil
+	}
+	if len(unreleased) == 0 {
+		return Version{}, fmt.Errorf("project %s has no unreleased version, the version must be given", project)
+	}
+	if !interactive {
+		return Version{}, &NotFoundError{Kind: "version", Options: names}
+	}
+	v, err := runInteractiveLoop(options{unreleased}, func(v interface{}) string {
+		return fmt.Sprintf("%s (%s)", v.(Version).Name, v.(Version).ID)
+	})
+	if err != nil {
+		return Version{}, err
+	}
+	return v.(Version), nil
+}